@@ -0,0 +1,177 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aykhans/dodo/types"
+)
+
+// accessLogTimeLayout is the layout of the timestamps of the common and combined log formats.
+const accessLogTimeLayout = "02/Jan/2006:15:04:05 -0700"
+
+// accessLogLineRegex matches a line of the common or combined log format of nginx and Apache:
+//
+//	127.0.0.1 - user [10/Oct/2000:13:55:36 -0700] "GET /index.html HTTP/1.1" 200 2326 "referer" "user agent"
+//
+// The referer and user agent are only present in the combined format, and any fields after them are ignored.
+var accessLogLineRegex = regexp.MustCompile(
+	`^\S+ \S+ .*?\[([^\]]+)\] "((?:[^"\\]|\\.)*)" \S+ \S+(?: "((?:[^"\\]|\\.)*)" "((?:[^"\\]|\\.)*)")?`,
+)
+
+type accessLogEntry struct {
+	time    time.Time
+	request types.RequestDefinition
+}
+
+// ReadAccessLog reads the requests of the configured access log, in the common or combined log
+// format of nginx and Apache, into the requests to replay together with the time of each request
+// relative to the first one, so that they can be replayed with their original timing.
+// The requests are sorted by their time. Relative URLs are resolved against the top-level URL,
+// and the referer and user agent of the combined format are sent as headers.
+// Lines whose request isn't a valid request line, such as the ones of malformed requests, are skipped.
+// It does nothing if no access log is configured.
+func (config *Config) ReadAccessLog() error {
+	if config.AccessLog == nil || *config.AccessLog == "" {
+		return nil
+	}
+	if len(config.Replay) > 0 {
+		return errors.New("access log cannot be used together with requests file")
+	}
+
+	file, err := os.Open(*config.AccessLog)
+	if err != nil {
+		return errors.New("failed to read access log from " + *config.AccessLog)
+	}
+	defer func() { _ = file.Close() }()
+
+	var (
+		scanner    = bufio.NewScanner(file)
+		lineNumber = 0
+		entries    []accessLogEntry
+	)
+	scanner.Buffer(nil, maxRequestsFileLineSize)
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		entry, ok, err := config.parseAccessLogLine(line)
+		if err != nil {
+			return fmt.Errorf("access log line %d: %v", lineNumber, err)
+		}
+		if ok {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read access log from %s: %v", *config.AccessLog, err)
+	}
+	if len(entries) == 0 {
+		return errors.New("access log has no requests")
+	}
+
+	slices.SortStableFunc(entries, func(a, b accessLogEntry) int {
+		return a.time.Compare(b.time)
+	})
+	config.Replay = make(types.RequestDefinitions, len(entries))
+	config.ReplayOffsets = make([]time.Duration, len(entries))
+	for i, entry := range entries {
+		config.Replay[i] = entry.request
+		config.ReplayOffsets[i] = entry.time.Sub(entries[0].time)
+	}
+	return nil
+}
+
+// parseAccessLogLine parses a line of the access log. It reports false if the request of the line
+// isn't a valid request line, and returns an error if the line isn't in the common or combined log format.
+func (config *Config) parseAccessLogLine(line string) (accessLogEntry, bool, error) {
+	match := accessLogLineRegex.FindStringSubmatch(line)
+	if match == nil {
+		return accessLogEntry{}, false, errors.New("line is not in the common or combined log format")
+	}
+
+	requestTime, err := time.Parse(accessLogTimeLayout, match[1])
+	if err != nil {
+		return accessLogEntry{}, false, fmt.Errorf("invalid time %q", match[1])
+	}
+
+	// The request line is "METHOD URI PROTOCOL", or "METHOD URI" for HTTP/0.9.
+	requestLine := strings.Fields(unescapeAccessLogValue(match[2]))
+	if len(requestLine) < 2 || len(requestLine) > 3 || !isHTTPToken(requestLine[0]) {
+		return accessLogEntry{}, false, nil
+	}
+
+	var requestURL types.RequestURL
+	if err := requestURL.Set(requestLine[1]); err != nil {
+		return accessLogEntry{}, false, nil
+	}
+	if !requestURL.IsAbs() {
+		if config.URL == nil || !config.URL.IsAbs() {
+			return accessLogEntry{}, false, errors.New("url must be absolute when there is no top-level url to resolve it against")
+		}
+		requestURL.URL = *config.URL.ResolveReference(&requestURL.URL)
+	}
+	if requestURL.Scheme != "http" && requestURL.Scheme != "https" {
+		return accessLogEntry{}, false, nil
+	}
+
+	request := types.RequestDefinition{
+		Method: requestLine[0],
+		URL:    &requestURL,
+		Params: urlParams(requestURL.URL),
+	}
+	request.URL.RawQuery = ""
+	if referer := unescapeAccessLogValue(match[3]); referer != "" && referer != "-" {
+		request.Headers.AppendByKey("Referer", referer)
+	}
+	if userAgent := unescapeAccessLogValue(match[4]); userAgent != "" && userAgent != "-" {
+		request.Headers.AppendByKey("User-Agent", userAgent)
+	}
+	return accessLogEntry{time: requestTime, request: request}, true, nil
+}
+
+// unescapeAccessLogValue unescapes a quoted field of an access log, where nginx escapes
+// quotes, backslashes and non-printable characters as \xHH and Apache as \" and \\.
+func unescapeAccessLogValue(value string) string {
+	if !strings.Contains(value, `\`) {
+		return value
+	}
+
+	var builder strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i+1 >= len(value) {
+			builder.WriteByte(value[i])
+			continue
+		}
+		if value[i+1] == 'x' && i+3 < len(value) {
+			if code, err := strconv.ParseUint(value[i+2:i+4], 16, 8); err == nil {
+				builder.WriteByte(byte(code))
+				i += 3
+				continue
+			}
+		}
+		builder.WriteByte(value[i+1])
+		i++
+	}
+	return builder.String()
+}
+
+// isHTTPToken reports whether the value is a valid HTTP method token.
+func isHTTPToken(value string) bool {
+	for _, r := range value {
+		if r < '!' || r > '~' || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, r) {
+			return false
+		}
+	}
+	return value != ""
+}
@@ -0,0 +1,202 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aykhans/dodo/types"
+)
+
+func TestParseAccessLogLine(t *testing.T) {
+	requestTime := time.Date(2000, time.October, 10, 13, 55, 36, 0, time.FixedZone("", -7*60*60))
+
+	tests := []struct {
+		name    string
+		line    string
+		baseURL string
+		wantURL string
+		want    types.RequestDefinition
+	}{
+		{
+			name:    "common format",
+			line:    `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /index.html?a=1&a=2 HTTP/1.0" 200 2326`,
+			baseURL: "https://example.com/base/",
+			wantURL: "https://example.com/index.html",
+			want: types.RequestDefinition{
+				Method: "GET",
+				Params: types.Params{{Key: "a", Value: []string{"1"}}, {Key: "a", Value: []string{"2"}}},
+			},
+		},
+		{
+			// Replayed requests send their params in the order of the logged query.
+			name:    "query order",
+			line:    `127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET /search?sort=desc&q=a%2Bb&page=2&q=c&empty HTTP/1.1" 200 2326`,
+			baseURL: "https://example.com",
+			wantURL: "https://example.com/search",
+			want: types.RequestDefinition{
+				Method: "GET",
+				Params: types.Params{
+					{Key: "sort", Value: []string{"desc"}},
+					{Key: "q", Value: []string{"a+b"}},
+					{Key: "q", Value: []string{"c"}},
+					{Key: "page", Value: []string{"2"}},
+					{Key: "empty", Value: []string{""}},
+				},
+			},
+		},
+		{
+			name:    "relative path and no size",
+			line:    `::1 - - [10/Oct/2000:13:55:36 -0700] "DELETE items/1 HTTP/1.1" 204 -`,
+			baseURL: "http://example.com/api/",
+			wantURL: "http://example.com/api/items/1",
+			want:    types.RequestDefinition{Method: "DELETE", Params: types.Params{}},
+		},
+		{
+			name:    "combined format with extra fields",
+			line:    `10.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "POST /login HTTP/2.0" 302 0 "https://example.com/" "Mozilla/5.0 (X11)" "extra"`,
+			baseURL: "http://example.com",
+			wantURL: "http://example.com/login",
+			want: types.RequestDefinition{
+				Method: "POST",
+				Params: types.Params{},
+				Headers: types.Headers{
+					{Key: "Referer", Value: []string{"https://example.com/"}},
+					{Key: "User-Agent", Value: []string{"Mozilla/5.0 (X11)"}},
+				},
+			},
+		},
+		{
+			name:    "combined format without referer and user agent",
+			line:    `10.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET / HTTP/1.1" 200 12 "-" "-"`,
+			baseURL: "http://example.com",
+			wantURL: "http://example.com/",
+			want:    types.RequestDefinition{Method: "GET", Params: types.Params{}},
+		},
+		{
+			// nginx escapes quotes as \x22, Apache as \".
+			name:    "escaped quotes",
+			line:    `10.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET /a\x22b HTTP/1.1" 200 12 "-" "agent \"quoted\" \x5C"`,
+			baseURL: "http://example.com",
+			wantURL: "http://example.com/a%22b",
+			want: types.RequestDefinition{
+				Method:  "GET",
+				Params:  types.Params{},
+				Headers: types.Headers{{Key: "User-Agent", Value: []string{`agent "quoted" \`}}},
+			},
+		},
+		{
+			name:    "absolute URI without top-level URL",
+			line:    `10.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET http://other.com:8080/x?q=1 HTTP/1.1" 200 12`,
+			wantURL: "http://other.com:8080/x",
+			want: types.RequestDefinition{
+				Method: "GET",
+				Params: types.Params{{Key: "q", Value: []string{"1"}}},
+			},
+		},
+		{
+			name:    "HTTP/0.9 request line",
+			line:    `10.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET /old" 200 12`,
+			baseURL: "http://example.com",
+			wantURL: "http://example.com/old",
+			want:    types.RequestDefinition{Method: "GET", Params: types.Params{}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := NewConfig()
+			if test.baseURL != "" {
+				config.URL = testRequestURL(t, test.baseURL)
+			}
+
+			entry, ok, err := config.parseAccessLogLine(test.line)
+			if err != nil || !ok {
+				t.Fatalf("parseAccessLogLine(%q) = %v, %v, want a request", test.line, ok, err)
+			}
+			if !entry.time.Equal(requestTime) {
+				t.Errorf("parseAccessLogLine(%q) time = %v, want %v", test.line, entry.time, requestTime)
+			}
+			if got := entry.request.URL.String(); got != test.wantURL {
+				t.Errorf("parseAccessLogLine(%q) URL = %q, want %q", test.line, got, test.wantURL)
+			}
+			entry.request.URL = nil
+			if !reflect.DeepEqual(entry.request, test.want) {
+				t.Errorf("parseAccessLogLine(%q) = %s params=%v headers=%v, want %s params=%v headers=%v", test.line,
+					entry.request.Method, entry.request.Params, entry.request.Headers, test.want.Method, test.want.Params, test.want.Headers)
+			}
+		})
+	}
+}
+
+func TestParseAccessLogLineSkipsInvalidRequests(t *testing.T) {
+	// These are logged for requests that were rejected before they could be parsed.
+	lines := []string{
+		`10.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "\x16\x03\x01\x02\x00\x01\x00\x01\xFC\x03\x03" 400 157 "-" "-"`,
+		`10.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "-" 408 0`,
+		`10.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GE(T / HTTP/1.1" 400 0`,
+		`10.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET / HTTP/1.1 x" 400 0`,
+		`10.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET ftp://example.com/file HTTP/1.1" 400 0`,
+	}
+
+	config := NewConfig()
+	config.URL = testRequestURL(t, "http://example.com")
+	for _, line := range lines {
+		if _, ok, err := config.parseAccessLogLine(line); ok || err != nil {
+			t.Errorf("parseAccessLogLine(%q) = %v, %v, want the line to be skipped", line, ok, err)
+		}
+	}
+}
+
+func TestParseAccessLogLineErrors(t *testing.T) {
+	tests := []struct {
+		line    string
+		wantErr string
+	}{
+		{line: `GET /index.html 200`, wantErr: "not in the common or combined log format"},
+		{line: `10.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET / HTTP/1.1" 200`, wantErr: "not in the common or combined log format"},
+		{line: `10.0.0.1 - - [2000-10-10T13:55:36Z] "GET / HTTP/1.1" 200 0`, wantErr: `invalid time "2000-10-10T13:55:36Z"`},
+		{line: `10.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET / HTTP/1.1" 200 0`, wantErr: "url must be absolute"},
+	}
+
+	for _, test := range tests {
+		_, _, err := NewConfig().parseAccessLogLine(test.line)
+		if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+			t.Errorf("parseAccessLogLine(%q) error = %v, want it to contain %q", test.line, err, test.wantErr)
+		}
+	}
+}
+
+func TestUnescapeAccessLogValue(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "", want: ""},
+		{value: "plain value", want: "plain value"},
+		{value: `\x22quoted\x22`, want: `"quoted"`},
+		{value: `\"quoted\"`, want: `"quoted"`},
+		{value: `back\\slash`, want: `back\slash`},
+		{value: `\x5C\x5c`, want: `\\`},
+		{value: `\x16\x03`, want: "\x16\x03"},
+		{value: `\xZZ`, want: "xZZ"},
+		{value: `\x4`, want: "x4"},
+		{value: `trailing\`, want: `trailing\`},
+	}
+
+	for _, test := range tests {
+		if got := unescapeAccessLogValue(test.value); got != test.want {
+			t.Errorf("unescapeAccessLogValue(%q) = %q, want %q", test.value, got, test.want)
+		}
+	}
+}
+
+func testRequestURL(t *testing.T, value string) *types.RequestURL {
+	t.Helper()
+	var requestURL types.RequestURL
+	if err := requestURL.Set(value); err != nil {
+		t.Fatalf("invalid URL %q: %v", value, err)
+	}
+	return &requestURL
+}
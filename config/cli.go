@@ -3,6 +3,7 @@ package config
 import (
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"time"
@@ -25,7 +26,7 @@ Usage with config file:
 Usage with all flags:
   dodo -f /path/to/config/file/config.json \
     -u https://example.com -m POST \
-    -d 10 -r 1000 -o 3m -t 3s \
+    -d 10 -r 1000 -o 3m -t 3s -rate 200 \
     -b "body1" -body "body2" \
     -H "header1:value1" -header "header2:value2" \
     -p "param1=value1" -param "param2=value2" \
@@ -34,22 +35,46 @@ Usage with all flags:
     -skip-verify -y
 
 Flags:
-  -h, -help                   help for dodo
-  -v, -version                version for dodo
-  -y, -yes          bool      Answer yes to all questions (default %v)
-  -f, -config-file  string    Path to the local config file or http(s) URL of the config file
-  -d, -dodos        uint      Number of dodos(threads) (default %d)
-  -r, -requests     uint      Number of total requests
-  -o, -duration     Time      Maximum duration for the test (e.g. 30s, 1m, 5h)
-  -t, -timeout      Time      Timeout for each request (e.g. 400ms, 15s, 1m10s) (default %v)
-  -u, -url          string    URL for stress testing
-  -m, -method       string    HTTP Method for the request (default %s)
-  -b, -body         [string]  Body for the request (e.g. "body text")
-  -p, -param        [string]  Parameter for the request (e.g. "key1=value1")
-  -H, -header       [string]  Header for the request (e.g. "key1:value1")
-  -c, -cookie       [string]  Cookie for the request (e.g. "key1=value1")
-  -x, -proxy        [string]  Proxy for the request (e.g. "http://proxy.example.com:8080")
-  -skip-verify      bool      Skip SSL/TLS certificate verification (default %v)`
+  -h, -help                         help for dodo
+  -v, -version                      version for dodo
+  -y, -yes                bool      Answer yes to all questions (default %v)
+  -f, -config-file        string    Path to the local config file or http(s) URL of the config file
+  -d, -dodos              uint      Number of dodos(threads) (default %d)
+  -r, -requests           uint      Number of total requests (iterations when a scenario is set)
+  -o, -duration           Time      Maximum duration for the test (e.g. 30s, 1m, 5h)
+  -t, -timeout            Time      Timeout for each request (e.g. 400ms, 15s, 1m10s) (default %v)
+  -rate                   uint      Target requests (iterations with a scenario) per second shared by all dodos (default unlimited)
+  -arrival                string    Open model arrival process: constant, poisson or uniform (requires rate)
+  -percentiles            string    Comma separated latency percentiles to report (default %s)
+  -histogram-precision    uint      Significant figures kept by the latency histograms, 1-5 (default %d)
+  -interval               Time      Interval of the time series summary (e.g. 1s, 10s)
+  -seed                   int       Seed of the random values, to repeat the requests of a run (default random)
+  -output                 string    Format of the final report: table or json (default %s)
+  -output-file            string    Write the final report to the file instead of stdout
+  -csv-file               string    Stream a row for every request to the CSV file
+  -requests-file          string    Replay the requests of the JSONL file, one request per line
+  -requests-order         string    Order of the replayed requests: sequential, random or round-robin (default %s)
+  -data                   string    CSV or JSONL file whose rows the templates can use as {{ .row.<column> }}
+  -data-mode              string    How the dodos pick the rows: sequential, random, unique or once (default %s)
+  -access-log             string    Replay the requests of the nginx/Apache access log with their original timing
+  -access-log-speed       float     Speed factor of the access log replay (e.g. 2 for twice as fast) (default %v)
+  -har                    string    Import the requests of the HAR file as the steps of the scenario
+  -har-hosts              string    Comma separated hosts of the imported HAR requests (e.g. "api.example.com,*.example.org")
+  -har-types              string    Comma separated resource types of the imported HAR requests (e.g. "xhr,fetch")
+  -openapi                string    Import the operations of the OpenAPI 3 spec as the requests of the mix
+  -openapi-operations     string    Comma separated operationIds or "METHOD /path" of the imported OpenAPI operations
+  -curl                   string    Import the request of the curl command (e.g. "curl -X POST https://example.com -d 'a=b'")
+  -export-config          string    Write the config with the imported requests as YAML to the file ("-" for stdout) and exit
+  -threshold              [string]  Pass/fail rule checked after the run (e.g. "p95 < 300ms", "error_rate < 1%%")
+  -u, -url                string    URL for stress testing
+  -m, -method             string    HTTP Method for the request (default %s)
+  -b, -body               [string]  Body for the request (e.g. "body text")
+  -var                    [string]  Variable evaluated once per request as {{ .vars.name }} (e.g. "id={{ fakeit_UUID }}")
+  -p, -param              [string]  Parameter for the request (e.g. "key1=value1")
+  -H, -header             [string]  Header for the request (e.g. "key1:value1")
+  -c, -cookie             [string]  Cookie for the request (e.g. "key1=value1")
+  -x, -proxy              [string]  Proxy for the request (e.g. "http://proxy.example.com:8080")
+  -skip-verify            bool      Skip SSL/TLS certificate verification (default %v)`
 
 func (config *Config) ReadCLI() (types.ConfigFile, error) {
 	flag.Usage = func() {
@@ -58,22 +83,49 @@ func (config *Config) ReadCLI() (types.ConfigFile, error) {
 			DefaultYes,
 			DefaultDodosCount,
 			DefaultTimeout,
+			DefaultPercentiles.String(),
+			DefaultHistogramSF,
+			DefaultOutput,
+			DefaultRequestsOrder,
+			DefaultDataMode,
+			DefaultAccessLogSpeed,
 			DefaultMethod,
 			DefaultSkipVerify,
 		)
 	}
 
 	var (
-		version      = false
-		configFile   = ""
-		yes          = false
-		skipVerify   = false
-		method       = ""
-		url          types.RequestURL
-		dodosCount   = uint(0)
-		requestCount = uint(0)
-		timeout      time.Duration
-		duration     time.Duration
+		version        = false
+		configFile     = ""
+		yes            = false
+		skipVerify     = false
+		method         = ""
+		url            types.RequestURL
+		dodosCount     = uint(0)
+		requestCount   = uint(0)
+		rate           = uint(0)
+		arrival        = ""
+		histogramSF    = uint(0)
+		timeout        time.Duration
+		duration       time.Duration
+		interval       time.Duration
+		seed           = int64(0)
+		output         = ""
+		outputFile     = ""
+		csvFile        = ""
+		requestsFile   = ""
+		requestsOrder  = ""
+		dataFile       = ""
+		dataMode       = ""
+		accessLog      = ""
+		accessLogSpeed = float64(0)
+		har            = ""
+		harHosts       = ""
+		harTypes       = ""
+		openAPI        = ""
+		openAPIOps     = ""
+		curl           = ""
+		exportConfig   = ""
 	)
 
 	{
@@ -97,15 +149,63 @@ func (config *Config) ReadCLI() (types.ConfigFile, error) {
 		flag.UintVar(&dodosCount, "dodos", 0, "Number of dodos(threads)")
 		flag.UintVar(&dodosCount, "d", 0, "Number of dodos(threads)")
 
-		flag.UintVar(&requestCount, "requests", 0, "Number of total requests")
-		flag.UintVar(&requestCount, "r", 0, "Number of total requests")
+		flag.UintVar(&requestCount, "requests", 0, "Number of total requests (iterations when a scenario is set)")
+		flag.UintVar(&requestCount, "r", 0, "Number of total requests (iterations when a scenario is set)")
 
 		flag.DurationVar(&duration, "duration", 0, "Maximum duration of the test")
 		flag.DurationVar(&duration, "o", 0, "Maximum duration of the test")
 
+		flag.UintVar(&rate, "rate", 0, "Target requests per second")
+
+		flag.StringVar(&arrival, "arrival", "", "Open model arrival process")
+
+		flag.Var(&config.Percentiles, "percentiles", "Comma separated latency percentiles to report")
+
+		flag.UintVar(&histogramSF, "histogram-precision", 0, "Significant figures kept by the latency histograms")
+
+		flag.DurationVar(&interval, "interval", 0, "Interval of the time series summary")
+
+		flag.Int64Var(&seed, "seed", 0, "Seed of the random values")
+
+		flag.StringVar(&output, "output", "", "Format of the final report")
+
+		flag.StringVar(&outputFile, "output-file", "", "Write the final report to the file")
+
+		flag.StringVar(&csvFile, "csv-file", "", "Stream a row for every request to the CSV file")
+
+		flag.StringVar(&requestsFile, "requests-file", "", "Replay the requests of the JSONL file")
+
+		flag.StringVar(&requestsOrder, "requests-order", "", "Order of the replayed requests")
+
+		flag.StringVar(&dataFile, "data", "", "CSV or JSONL file whose rows the templates can use")
+
+		flag.StringVar(&dataMode, "data-mode", "", "How the dodos pick the rows of the data file")
+
+		flag.StringVar(&accessLog, "access-log", "", "Replay the requests of the access log")
+
+		flag.Float64Var(&accessLogSpeed, "access-log-speed", 0, "Speed factor of the access log replay")
+
+		flag.StringVar(&har, "har", "", "Import the requests of the HAR file")
+
+		flag.StringVar(&harHosts, "har-hosts", "", "Comma separated hosts of the imported HAR requests")
+
+		flag.StringVar(&harTypes, "har-types", "", "Comma separated resource types of the imported HAR requests")
+
+		flag.StringVar(&openAPI, "openapi", "", "Import the operations of the OpenAPI 3 spec")
+
+		flag.StringVar(&openAPIOps, "openapi-operations", "", "Comma separated operations of the imported OpenAPI spec")
+
+		flag.StringVar(&curl, "curl", "", "Import the request of the curl command")
+
+		flag.StringVar(&exportConfig, "export-config", "", "Write the config as YAML to the file and exit")
+
+		flag.Var(&config.Thresholds, "threshold", "Pass/fail rule checked after the run")
+
 		flag.DurationVar(&timeout, "timeout", 0, "Timeout for each request (e.g. 400ms, 15s, 1m10s)")
 		flag.DurationVar(&timeout, "t", 0, "Timeout for each request (e.g. 400ms, 15s, 1m10s)")
 
+		flag.Var(&config.Vars, "var", "Variable evaluated once per request")
+
 		flag.Var(&config.Params, "param", "URL parameter to send with the request")
 		flag.Var(&config.Params, "p", "URL parameter to send with the request")
 
@@ -150,6 +250,54 @@ func (config *Config) ReadCLI() (types.ConfigFile, error) {
 			config.RequestCount = utils.ToPtr(requestCount)
 		case "duration", "o":
 			config.Duration = &types.Duration{Duration: duration}
+		case "rate":
+			config.Rate = utils.ToPtr(rate)
+		case "arrival":
+			config.Arrival = utils.ToPtr(arrival)
+		case "histogram-precision":
+			config.HistogramSF = utils.ToPtr(histogramSF)
+		case "interval":
+			config.Interval = &types.Duration{Duration: interval}
+		case "seed":
+			config.Seed = utils.ToPtr(seed)
+		case "output":
+			config.Output = utils.ToPtr(output)
+		case "output-file":
+			config.OutputFile = utils.ToPtr(outputFile)
+		case "csv-file":
+			config.CSVFile = utils.ToPtr(csvFile)
+		case "requests-file":
+			config.RequestsFile = utils.ToPtr(requestsFile)
+		case "requests-order":
+			config.RequestsOrder = utils.ToPtr(requestsOrder)
+		case "data":
+			if config.Data == nil {
+				config.Data = &types.DataSource{}
+			}
+			config.Data.File = dataFile
+		case "data-mode":
+			if config.Data == nil {
+				config.Data = &types.DataSource{}
+			}
+			config.Data.Mode = dataMode
+		case "access-log":
+			config.AccessLog = utils.ToPtr(accessLog)
+		case "access-log-speed":
+			config.AccessLogSpeed = utils.ToPtr(accessLogSpeed)
+		case "har":
+			config.HAR = utils.ToPtr(har)
+		case "har-hosts":
+			config.HARHosts = splitCommaSeparated(harHosts)
+		case "har-types":
+			config.HARTypes = splitCommaSeparated(harTypes)
+		case "openapi":
+			config.OpenAPI = utils.ToPtr(openAPI)
+		case "openapi-operations":
+			config.OpenAPIOperations = splitCommaSeparated(openAPIOps)
+		case "curl":
+			config.Curl = utils.ToPtr(curl)
+		case "export-config":
+			config.ExportConfig = utils.ToPtr(exportConfig)
 		case "timeout", "t":
 			config.Timeout = &types.Timeout{Duration: timeout}
 		case "yes", "y":
@@ -162,19 +310,30 @@ func (config *Config) ReadCLI() (types.ConfigFile, error) {
 	return types.ConfigFile(configFile), nil
 }
 
+// splitCommaSeparated returns the trimmed, non-empty values of the comma separated list.
+func splitCommaSeparated(value string) []string {
+	var values []string
+	for item := range strings.SplitSeq(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+	return values
+}
+
 // CLIYesOrNoReader reads a yes or no answer from the command line.
-// It prompts the user with the given message and default value,
+// It prompts the user on the given writer with the given message and default value,
 // and returns true if the user answers "y" or "Y", and false otherwise.
 // If there is an error while reading the input, it returns false.
 // If the user simply presses enter without providing any input,
 // it returns the default value specified by the `dft` parameter.
-func CLIYesOrNoReader(message string, dft bool) bool {
+func CLIYesOrNoReader(w io.Writer, message string, dft bool) bool {
 	var answer string
 	defaultMessage := "Y/n"
 	if !dft {
 		defaultMessage = "y/N"
 	}
-	fmt.Printf("%s [%s]: ", message, defaultMessage)
+	fmt.Fprintf(w, "%s [%s]: ", message, defaultMessage)
 	if _, err := fmt.Scanln(&answer); err != nil {
 		if err.Error() == "unexpected newline" {
 			return dft
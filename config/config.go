@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"math/rand"
 	"net/url"
 	"os"
+	"regexp"
 	"slices"
 	"strings"
 	"text/template"
@@ -18,51 +20,243 @@ import (
 )
 
 const (
-	VERSION             string        = "0.7.3"
-	DefaultUserAgent    string        = "Dodo/" + VERSION
-	DefaultMethod       string        = "GET"
-	DefaultTimeout      time.Duration = time.Second * 10
-	DefaultDodosCount   uint          = 1
-	DefaultRequestCount uint          = 0
-	DefaultDuration     time.Duration = 0
-	DefaultYes          bool          = false
-	DefaultSkipVerify   bool          = false
+	VERSION               string        = "0.7.3"
+	DefaultUserAgent      string        = "Dodo/" + VERSION
+	DefaultMethod         string        = "GET"
+	DefaultTimeout        time.Duration = time.Second * 10
+	DefaultDodosCount     uint          = 1
+	DefaultRequestCount   uint          = 0
+	DefaultRate           uint          = 0
+	DefaultArrival        string        = ""
+	DefaultHistogramSF    uint          = 3
+	DefaultDuration       time.Duration = 0
+	DefaultInterval       time.Duration = 0
+	DefaultOutput         string        = OutputTable
+	DefaultOutputFile     string        = ""
+	DefaultCSVFile        string        = ""
+	DefaultRequestsFile   string        = ""
+	DefaultRequestsOrder  string        = RequestsOrderSequential
+	DefaultAccessLog      string        = ""
+	DefaultAccessLogSpeed float64       = 1
+	DefaultDataMode       string        = DataModeSequential
+	DefaultYes            bool          = false
+	DefaultSkipVerify     bool          = false
 )
 
-var SupportedProxySchemes []string = []string{"http", "socks5", "socks5h"}
+const (
+	ArrivalConstant string = "constant"
+	ArrivalPoisson  string = "poisson"
+	ArrivalUniform  string = "uniform"
+)
+
+const (
+	OutputTable string = "table"
+	OutputJSON  string = "json"
+)
+
+const (
+	RequestsOrderSequential string = "sequential"
+	RequestsOrderRandom     string = "random"
+	RequestsOrderRoundRobin string = "round-robin"
+)
+
+const (
+	DataModeSequential string = "sequential"
+	DataModeRandom     string = "random"
+	DataModeUnique     string = "unique"
+	DataModeOnce       string = "once"
+)
+
+var (
+	SupportedProxySchemes   []string          = []string{"http", "socks5", "socks5h"}
+	SupportedArrivals       []string          = []string{ArrivalConstant, ArrivalPoisson, ArrivalUniform}
+	SupportedOutputs        []string          = []string{OutputTable, OutputJSON}
+	SupportedRequestsOrders []string          = []string{RequestsOrderSequential, RequestsOrderRandom, RequestsOrderRoundRobin}
+	SupportedDataModes      []string          = []string{DataModeSequential, DataModeRandom, DataModeUnique, DataModeOnce}
+	DefaultPercentiles      types.Percentiles = types.Percentiles{90, 95, 99}
+)
 
 type RequestConfig struct {
-	Method       string
-	URL          url.URL
-	Timeout      time.Duration
-	DodosCount   uint
-	RequestCount uint
-	Duration     time.Duration
-	Yes          bool
-	SkipVerify   bool
-	Params       types.Params
-	Headers      types.Headers
-	Cookies      types.Cookies
-	Body         types.Body
-	Proxies      types.Proxies
+	Method         string
+	URL            url.URL
+	Timeout        time.Duration
+	DodosCount     uint
+	RequestCount   uint
+	Duration       time.Duration
+	Rate           uint
+	Stages         types.Stages
+	Arrival        string
+	HistogramSF    uint
+	Percentiles    types.Percentiles
+	Interval       time.Duration
+	Seed           int64
+	Output         string
+	OutputFile     string
+	CSVFile        string
+	Thresholds     types.Thresholds
+	Yes            bool
+	SkipVerify     bool
+	Vars           types.Vars
+	Params         types.Params
+	Headers        types.Headers
+	Cookies        types.Cookies
+	Body           types.Body
+	Proxies        types.Proxies
+	Checks         types.Checks
+	Extract        types.Extractors
+	Scenario       types.RequestDefinitions
+	Mix            types.RequestDefinitions
+	RequestsFile   string
+	RequestsOrder  string
+	Replay         types.RequestDefinitions
+	AccessLog      string
+	AccessLogSpeed float64
+	ReplayOffsets  []time.Duration
+	Data           types.DataSource
+	DataRows       []map[string]any
 }
 
+// NewRequestConfig creates the request config of the run from the validated config.
+// A requests file replayed in sequential order without a request count or duration
+// is replayed once, so its request count is the number of requests in the file.
 func NewRequestConfig(conf *Config) *RequestConfig {
+	var requestURL url.URL
+	if conf.URL != nil {
+		requestURL = conf.URL.URL
+	}
+
+	var data types.DataSource
+	if conf.Data != nil {
+		data = types.DataSource{File: conf.Data.File, Mode: conf.GetDataMode()}
+	}
+
+	requestCount := *conf.RequestCount
+	if requestCount == 0 && conf.Duration.Duration == 0 {
+		if len(conf.Replay) > 0 && *conf.RequestsOrder == RequestsOrderSequential {
+			requestCount = uint(len(conf.Replay))
+		} else if len(conf.DataRows) > 0 && data.Mode == DataModeOnce {
+			requestCount = uint(len(conf.DataRows))
+		}
+	}
+
 	return &RequestConfig{
-		Method:       *conf.Method,
-		URL:          conf.URL.URL,
-		Timeout:      conf.Timeout.Duration,
-		DodosCount:   *conf.DodosCount,
-		RequestCount: *conf.RequestCount,
-		Duration:     conf.Duration.Duration,
-		Yes:          *conf.Yes,
-		SkipVerify:   *conf.SkipVerify,
-		Params:       conf.Params,
-		Headers:      conf.Headers,
-		Cookies:      conf.Cookies,
-		Body:         conf.Body,
-		Proxies:      conf.Proxies,
+		Method:         *conf.Method,
+		URL:            requestURL,
+		Timeout:        conf.Timeout.Duration,
+		DodosCount:     *conf.DodosCount,
+		RequestCount:   requestCount,
+		Duration:       conf.Duration.Duration,
+		Rate:           *conf.Rate,
+		Stages:         conf.Stages,
+		Arrival:        *conf.Arrival,
+		HistogramSF:    *conf.HistogramSF,
+		Percentiles:    conf.Percentiles,
+		Interval:       conf.Interval.Duration,
+		Seed:           *conf.Seed,
+		Output:         *conf.Output,
+		OutputFile:     *conf.OutputFile,
+		CSVFile:        *conf.CSVFile,
+		Thresholds:     conf.Thresholds,
+		Yes:            *conf.Yes,
+		SkipVerify:     *conf.SkipVerify,
+		Vars:           conf.Vars,
+		Params:         conf.Params,
+		Headers:        conf.Headers,
+		Cookies:        conf.Cookies,
+		Body:           conf.Body,
+		Proxies:        conf.Proxies,
+		Checks:         conf.Checks,
+		Extract:        conf.Extract,
+		Scenario:       resolveDefinitions(conf, conf.Scenario),
+		Mix:            resolveDefinitions(conf, conf.Mix),
+		RequestsFile:   *conf.RequestsFile,
+		RequestsOrder:  *conf.RequestsOrder,
+		Replay:         conf.Replay,
+		AccessLog:      *conf.AccessLog,
+		AccessLogSpeed: *conf.AccessLogSpeed,
+		ReplayOffsets:  conf.ReplayOffsets,
+		Data:           data,
+		DataRows:       conf.DataRows,
+	}
+}
+
+// resolveDefinitions returns the request definitions of the config (the scenario steps or
+// the requests of the mix) with their unset method, URL and body filled from the top-level
+// request, relative URLs resolved against the top-level URL, and the top-level vars, params,
+// headers, cookies, checks and extractors prepended to their own.
+// Requests without a name are named after their method and path, numbered if the name is taken.
+func resolveDefinitions(conf *Config, definitions types.RequestDefinitions) types.RequestDefinitions {
+	if len(definitions) == 0 {
+		return nil
+	}
+
+	var (
+		resolvedDefinitions = make(types.RequestDefinitions, len(definitions))
+		names               = make(map[string]int, len(definitions))
+	)
+	for i, definition := range definitions {
+		resolved := types.RequestDefinition{
+			Name:    definition.Name,
+			Weight:  definition.Weight,
+			Method:  definition.Method,
+			URL:     definition.URL,
+			Vars:    append(slices.Clone(conf.Vars), definition.Vars...),
+			Params:  append(slices.Clone(conf.Params), definition.Params...),
+			Headers: append(slices.Clone(conf.Headers), definition.Headers...),
+			Cookies: append(slices.Clone(conf.Cookies), definition.Cookies...),
+			Body:    definition.Body,
+			Checks:  append(slices.Clone(conf.Checks), definition.Checks...),
+			Extract: append(slices.Clone(conf.Extract), definition.Extract...),
+		}
+		if resolved.Method == "" {
+			resolved.Method = *conf.Method
+		}
+		switch {
+		case resolved.URL == nil:
+			resolved.URL = conf.URL
+		case !resolved.URL.IsAbs() && conf.URL != nil:
+			resolved.URL = &types.RequestURL{URL: *conf.URL.ResolveReference(&resolved.URL.URL)}
+		}
+		if len(resolved.Body) == 0 {
+			resolved.Body = conf.Body
+		}
+
+		if resolved.Name == "" {
+			resolved.Name = resolved.Method + " " + resolved.URL.Path
+			if names[resolved.Name] > 0 {
+				resolved.Name = fmt.Sprintf("%s #%d", resolved.Name, names[resolved.Name]+1)
+			}
+		}
+		names[resolved.Name]++
+		resolvedDefinitions[i] = resolved
 	}
+	return resolvedDefinitions
+}
+
+// GetRequestDefinitions returns the requests sent by each dodo: the steps of the scenario,
+// the requests of the mix, the requests of the requests file, or the top-level request without a name.
+func (rc *RequestConfig) GetRequestDefinitions() types.RequestDefinitions {
+	if len(rc.Scenario) > 0 {
+		return rc.Scenario
+	}
+	if len(rc.Mix) > 0 {
+		return rc.Mix
+	}
+	if len(rc.Replay) > 0 {
+		return rc.Replay
+	}
+
+	return types.RequestDefinitions{{
+		Method:  rc.Method,
+		URL:     &types.RequestURL{URL: rc.URL},
+		Vars:    rc.Vars,
+		Params:  rc.Params,
+		Headers: rc.Headers,
+		Cookies: rc.Cookies,
+		Body:    rc.Body,
+		Checks:  rc.Checks,
+		Extract: rc.Extract,
+	}}
 }
 
 func (rc *RequestConfig) GetValidDodosCountForRequests() uint {
@@ -72,16 +266,35 @@ func (rc *RequestConfig) GetValidDodosCountForRequests() uint {
 	return min(rc.DodosCount, rc.RequestCount)
 }
 
+// LogWriter returns the writer for everything other than the final report, such as
+// the config table and the progress bar. It is stderr if the JSON report is written
+// to stdout, so that stdout only contains the report, and stdout otherwise.
+func (rc *RequestConfig) LogWriter() io.Writer {
+	if rc.Output == OutputJSON && rc.OutputFile == "" {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// GetDuration returns the maximum duration of the run.
+// If stages are set, it is the sum of the stage durations.
+func (rc *RequestConfig) GetDuration() time.Duration {
+	if len(rc.Stages) > 0 {
+		return rc.Stages.TotalDuration()
+	}
+	return rc.Duration
+}
+
 func (rc *RequestConfig) GetMaxConns(minConns uint) uint {
 	maxConns := max(
-		minConns, rc.GetValidDodosCountForRequests(),
+		minConns, rc.GetValidDodosCountForRequests(), rc.Stages.MaxDodos(),
 	)
 	return ((maxConns * 50 / 100) + maxConns)
 }
 
 func (rc *RequestConfig) Print() {
 	t := table.NewWriter()
-	t.SetOutputMirror(os.Stdout)
+	t.SetOutputMirror(rc.LogWriter())
 	t.SetStyle(table.StyleLight)
 	t.SetColumnConfigs([]table.ColumnConfig{
 		{
@@ -107,7 +320,9 @@ func (rc *RequestConfig) Print() {
 	t.AppendSeparator()
 	t.AppendRow(table.Row{"Dodos", rc.DodosCount})
 	t.AppendSeparator()
-	if rc.RequestCount > 0 {
+	if rc.RequestCount > 0 && len(rc.Scenario) > 0 {
+		t.AppendRow(table.Row{"Requests", fmt.Sprintf("%d iterations", rc.RequestCount)})
+	} else if rc.RequestCount > 0 {
 		t.AppendRow(table.Row{"Requests", rc.RequestCount})
 	} else {
 		t.AppendRow(table.Row{"Requests"})
@@ -119,6 +334,48 @@ func (rc *RequestConfig) Print() {
 		t.AppendRow(table.Row{"Duration"})
 	}
 	t.AppendSeparator()
+	if rc.Rate > 0 && len(rc.Scenario) > 0 {
+		t.AppendRow(table.Row{"Rate", fmt.Sprintf("%d iterations/s", rc.Rate)})
+	} else if rc.Rate > 0 {
+		t.AppendRow(table.Row{"Rate", fmt.Sprintf("%d/s", rc.Rate)})
+	} else {
+		t.AppendRow(table.Row{"Rate"})
+	}
+	t.AppendSeparator()
+	if len(rc.Stages) > 0 {
+		t.AppendRow(table.Row{"Stages", rc.Stages.String()})
+		t.AppendSeparator()
+	}
+	if rc.Arrival != "" {
+		t.AppendRow(table.Row{"Arrival", rc.Arrival + " (open model)"})
+		t.AppendSeparator()
+	}
+	t.AppendRow(table.Row{"Percentiles", rc.Percentiles.String()})
+	t.AppendSeparator()
+	if rc.Interval > 0 {
+		t.AppendRow(table.Row{"Interval", rc.Interval})
+		t.AppendSeparator()
+	}
+	t.AppendRow(table.Row{"Seed", rc.Seed})
+	t.AppendSeparator()
+	if rc.OutputFile != "" {
+		t.AppendRow(table.Row{"Output", rc.Output + " (" + rc.OutputFile + ")"})
+	} else {
+		t.AppendRow(table.Row{"Output", rc.Output})
+	}
+	t.AppendSeparator()
+	if rc.CSVFile != "" {
+		t.AppendRow(table.Row{"CSV File", rc.CSVFile})
+		t.AppendSeparator()
+	}
+	if len(rc.Thresholds) > 0 {
+		t.AppendRow(table.Row{"Thresholds", rc.Thresholds.String()})
+		t.AppendSeparator()
+	}
+	if len(rc.Vars) > 0 {
+		t.AppendRow(table.Row{"Vars", rc.Vars.String()})
+		t.AppendSeparator()
+	}
 	t.AppendRow(table.Row{"Params", rc.Params.String()})
 	t.AppendSeparator()
 	t.AppendRow(table.Row{"Headers", rc.Headers.String()})
@@ -129,25 +386,100 @@ func (rc *RequestConfig) Print() {
 	t.AppendSeparator()
 	t.AppendRow(table.Row{"Body", rc.Body.String()})
 	t.AppendSeparator()
+	if len(rc.Checks) > 0 {
+		t.AppendRow(table.Row{"Checks", rc.Checks.String()})
+		t.AppendSeparator()
+	}
+	if len(rc.Extract) > 0 {
+		t.AppendRow(table.Row{"Extract", rc.Extract.String()})
+		t.AppendSeparator()
+	}
+	if len(rc.Scenario) > 0 {
+		t.AppendRow(table.Row{"Scenario", rc.Scenario.String()})
+		t.AppendSeparator()
+	}
+	if len(rc.Mix) > 0 {
+		t.AppendRow(table.Row{"Mix", rc.Mix.String()})
+		t.AppendSeparator()
+	}
+	if rc.RequestsFile != "" {
+		t.AppendRow(table.Row{
+			"Requests File",
+			fmt.Sprintf("%s (%d requests, %s)", rc.RequestsFile, len(rc.Replay), rc.RequestsOrder),
+		})
+		t.AppendSeparator()
+	}
+	if rc.Data.File != "" {
+		t.AppendRow(table.Row{
+			"Data",
+			fmt.Sprintf("%s (%d rows, %s)", rc.Data.File, len(rc.DataRows), rc.Data.Mode),
+		})
+		t.AppendSeparator()
+	}
+	if rc.AccessLog != "" {
+		t.AppendRow(table.Row{
+			"Access Log",
+			fmt.Sprintf("%s (%d requests, %gx speed)", rc.AccessLog, len(rc.Replay), rc.AccessLogSpeed),
+		})
+		t.AppendSeparator()
+	}
 	t.AppendRow(table.Row{"Skip Verify", rc.SkipVerify})
 
 	t.Render()
 }
 
 type Config struct {
-	Method       *string           `json:"method" yaml:"method"`
-	URL          *types.RequestURL `json:"url" yaml:"url"`
-	Timeout      *types.Timeout    `json:"timeout" yaml:"timeout"`
-	DodosCount   *uint             `json:"dodos" yaml:"dodos"`
-	RequestCount *uint             `json:"requests" yaml:"requests"`
-	Duration     *types.Duration   `json:"duration" yaml:"duration"`
-	Yes          *bool             `json:"yes" yaml:"yes"`
-	SkipVerify   *bool             `json:"skip_verify" yaml:"skip_verify"`
-	Params       types.Params      `json:"params" yaml:"params"`
-	Headers      types.Headers     `json:"headers" yaml:"headers"`
-	Cookies      types.Cookies     `json:"cookies" yaml:"cookies"`
-	Body         types.Body        `json:"body" yaml:"body"`
-	Proxies      types.Proxies     `json:"proxy" yaml:"proxy"`
+	Method            *string                  `json:"method" yaml:"method"`
+	URL               *types.RequestURL        `json:"url" yaml:"url"`
+	Timeout           *types.Timeout           `json:"timeout" yaml:"timeout"`
+	DodosCount        *uint                    `json:"dodos" yaml:"dodos"`
+	RequestCount      *uint                    `json:"requests" yaml:"requests"`
+	Duration          *types.Duration          `json:"duration" yaml:"duration"`
+	Rate              *uint                    `json:"rate" yaml:"rate"`
+	Stages            types.Stages             `json:"stages" yaml:"stages"`
+	Arrival           *string                  `json:"arrival" yaml:"arrival"`
+	HistogramSF       *uint                    `json:"histogram_precision" yaml:"histogram_precision"`
+	Percentiles       types.Percentiles        `json:"percentiles" yaml:"percentiles"`
+	Interval          *types.Duration          `json:"interval" yaml:"interval"`
+	Seed              *int64                   `json:"seed" yaml:"seed"`
+	Output            *string                  `json:"output" yaml:"output"`
+	OutputFile        *string                  `json:"output_file" yaml:"output_file"`
+	CSVFile           *string                  `json:"csv_file" yaml:"csv_file"`
+	Thresholds        types.Thresholds         `json:"thresholds" yaml:"thresholds"`
+	Yes               *bool                    `json:"yes" yaml:"yes"`
+	SkipVerify        *bool                    `json:"skip_verify" yaml:"skip_verify"`
+	Vars              types.Vars               `json:"vars" yaml:"vars"`
+	Params            types.Params             `json:"params" yaml:"params"`
+	Headers           types.Headers            `json:"headers" yaml:"headers"`
+	Cookies           types.Cookies            `json:"cookies" yaml:"cookies"`
+	Body              types.Body               `json:"body" yaml:"body"`
+	Proxies           types.Proxies            `json:"proxy" yaml:"proxy"`
+	Checks            types.Checks             `json:"checks" yaml:"checks"`
+	Extract           types.Extractors         `json:"extract" yaml:"extract"`
+	Scenario          types.RequestDefinitions `json:"scenario" yaml:"scenario"`
+	Mix               types.RequestDefinitions `json:"mix" yaml:"mix"`
+	RequestsFile      *string                  `json:"requests_file" yaml:"requests_file"`
+	RequestsOrder     *string                  `json:"requests_order" yaml:"requests_order"`
+	HAR               *string                  `json:"har" yaml:"har"`
+	HARHosts          []string                 `json:"har_hosts" yaml:"har_hosts"`
+	HARTypes          []string                 `json:"har_types" yaml:"har_types"`
+	OpenAPI           *string                  `json:"openapi" yaml:"openapi"`
+	OpenAPIOperations []string                 `json:"openapi_operations" yaml:"openapi_operations"`
+	AccessLog         *string                  `json:"access_log" yaml:"access_log"`
+	AccessLogSpeed    *float64                 `json:"access_log_speed" yaml:"access_log_speed"`
+	Data              *types.DataSource        `json:"data" yaml:"data"`
+
+	// Replay holds the requests read from the requests file by ReadRequestsFile.
+	Replay types.RequestDefinitions `json:"-" yaml:"-"`
+	// ReplayOffsets holds the times of the requests read from the access log by ReadAccessLog,
+	// relative to the first request.
+	ReplayOffsets []time.Duration `json:"-" yaml:"-"`
+	// DataRows holds the rows read from the data file by ReadData.
+	DataRows []map[string]any `json:"-" yaml:"-"`
+	// Curl is the curl command line the config is parsed from by ParseCurl.
+	Curl *string `json:"-" yaml:"-"`
+	// ExportConfig is the path WriteYAML writes the config to instead of running it, "-" for stdout.
+	ExportConfig *string `json:"-" yaml:"-"`
 }
 
 func NewConfig() *Config {
@@ -157,22 +489,15 @@ func NewConfig() *Config {
 func (config *Config) Validate() []error {
 	var errs []error
 	if utils.IsNilOrZero(config.URL) {
-		errs = append(errs, errors.New("request URL is required"))
+		if len(config.Scenario) == 0 && len(config.Mix) == 0 && len(config.Replay) == 0 {
+			errs = append(errs, errors.New("request URL is required"))
+		}
 	} else {
 		if config.URL.Scheme != "http" && config.URL.Scheme != "https" {
 			errs = append(errs, errors.New("request URL scheme must be http or https"))
 		}
 
-		urlParams := types.Params{}
-		for key, values := range config.URL.Query() {
-			for _, value := range values {
-				urlParams = append(urlParams, types.KeyValue[string, []string]{
-					Key:   key,
-					Value: []string{value},
-				})
-			}
-		}
-		config.Params = append(urlParams, config.Params...)
+		config.Params = append(urlParams(config.URL.URL), config.Params...)
 		config.URL.RawQuery = ""
 	}
 
@@ -185,10 +510,100 @@ func (config *Config) Validate() []error {
 	if utils.IsNilOrZero(config.DodosCount) {
 		errs = append(errs, errors.New("dodos count must be greater than 0"))
 	}
-	if utils.IsNilOrZero(config.Duration) && utils.IsNilOrZero(config.RequestCount) {
+	if len(config.Stages) > 0 {
+		if !utils.IsNilOrZero(config.Duration) || !utils.IsNilOrZero(config.RequestCount) {
+			errs = append(errs, errors.New("stages cannot be used together with duration or request count"))
+		}
+		if config.Stages.TargetsRate() && !utils.IsNilOrZero(config.Rate) {
+			errs = append(errs, errors.New("rate cannot be used together with rate targeted stages"))
+		}
+	} else if utils.IsNilOrZero(config.Duration) && utils.IsNilOrZero(config.RequestCount) &&
+		(len(config.Replay) == 0 || *config.RequestsOrder != RequestsOrderSequential) &&
+		(len(config.DataRows) == 0 || config.GetDataMode() != DataModeOnce) {
 		errs = append(errs, errors.New("you should provide at least one of duration or request count"))
 	}
 
+	if !utils.IsNilOrZero(config.Arrival) {
+		if !slices.Contains(SupportedArrivals, *config.Arrival) {
+			errs = append(errs,
+				fmt.Errorf("unsupported arrival \"%s\" (supported arrivals: %s)",
+					*config.Arrival, strings.Join(SupportedArrivals, ", "),
+				),
+			)
+		}
+		if len(config.Stages) > 0 && !config.Stages.TargetsRate() {
+			errs = append(errs, errors.New("arrival cannot be used together with dodos targeted stages"))
+		} else if len(config.Stages) == 0 && utils.IsNilOrZero(config.Rate) {
+			errs = append(errs, errors.New("arrival requires rate or rate targeted stages"))
+		}
+	}
+
+	if config.HistogramSF != nil && (*config.HistogramSF < 1 || *config.HistogramSF > 5) {
+		errs = append(errs, errors.New("histogram precision must be between 1 and 5"))
+	}
+	for i, percentile := range config.Percentiles {
+		if percentile <= 0 || percentile > 100 {
+			errs = append(errs, fmt.Errorf("percentiles[%d]: percentile must be greater than 0 and at most 100", i))
+		}
+	}
+	if config.Interval != nil && config.Interval.Duration < 0 {
+		errs = append(errs, errors.New("interval cannot be negative"))
+	}
+	if config.RequestsOrder != nil && !slices.Contains(SupportedRequestsOrders, *config.RequestsOrder) {
+		errs = append(errs,
+			fmt.Errorf("unsupported requests order \"%s\" (supported orders: %s)",
+				*config.RequestsOrder, strings.Join(SupportedRequestsOrders, ", "),
+			),
+		)
+	}
+	if utils.IsNilOrZero(config.HAR) && (len(config.HARHosts) > 0 || len(config.HARTypes) > 0) {
+		errs = append(errs, errors.New("HAR hosts and types can only be used together with a HAR file"))
+	}
+	if utils.IsNilOrZero(config.OpenAPI) && len(config.OpenAPIOperations) > 0 {
+		errs = append(errs, errors.New("OpenAPI operations can only be used together with an OpenAPI spec"))
+	}
+	if len(config.Replay) > 0 && (len(config.Scenario) > 0 || len(config.Mix) > 0) {
+		errs = append(errs, errors.New("requests file cannot be used together with scenario or mix"))
+	}
+	if config.Data != nil {
+		errs = append(errs, config.validateData()...)
+	}
+	if config.AccessLogSpeed != nil && *config.AccessLogSpeed <= 0 {
+		errs = append(errs, errors.New("access log speed must be greater than 0"))
+	}
+	if len(config.ReplayOffsets) > 0 {
+		if !utils.IsNilOrZero(config.Rate) || !utils.IsNilOrZero(config.Arrival) || len(config.Stages) > 0 {
+			errs = append(errs, errors.New("access log cannot be used together with rate, arrival or stages"))
+		}
+		if *config.RequestsOrder != RequestsOrderSequential {
+			errs = append(errs, errors.New("access log can only be replayed in sequential order"))
+		}
+	}
+	if config.Output != nil && !slices.Contains(SupportedOutputs, *config.Output) {
+		errs = append(errs,
+			fmt.Errorf("unsupported output \"%s\" (supported outputs: %s)",
+				*config.Output, strings.Join(SupportedOutputs, ", "),
+			),
+		)
+	}
+
+	for i, stage := range config.Stages {
+		if stage.Duration.Duration <= 0 {
+			errs = append(errs, fmt.Errorf("stages[%d]: duration must be greater than 0", i))
+		}
+		switch {
+		case stage.Dodos == nil && stage.Rate == nil:
+			errs = append(errs, fmt.Errorf("stages[%d]: one of dodos or rate is required", i))
+		case stage.Dodos != nil && stage.Rate != nil:
+			errs = append(errs, fmt.Errorf("stages[%d]: dodos and rate cannot be used together", i))
+		case stage.TargetsRate() != config.Stages.TargetsRate():
+			errs = append(errs, fmt.Errorf("stages[%d]: all stages must target either dodos or rate", i))
+		}
+	}
+
+	errs = append(errs, validateChecks("checks", config.Checks)...)
+	errs = append(errs, validateExtractors("extract", config.Extract)...)
+
 	for i, proxy := range config.Proxies {
 		if proxy.String() == "" {
 			errs = append(errs, fmt.Errorf("proxies[%d]: proxy cannot be empty", i))
@@ -201,21 +616,231 @@ func (config *Config) Validate() []error {
 		}
 	}
 
-	funcMap := *utils.NewFuncMapGenerator(
-		rand.New(
-			rand.NewSource(
-				time.Now().UnixNano(),
+	funcMap := *utils.NewFuncMapGenerator(config.newRand()).GetFuncMap()
+
+	errs = append(errs, validateTemplates(funcMap, config.Vars, config.Params, config.Headers, config.Cookies, config.Body)...)
+
+	if len(config.Scenario) > 0 && len(config.Mix) > 0 {
+		errs = append(errs, errors.New("scenario and mix cannot be used together"))
+	}
+	errs = append(errs, config.validateDefinitions("scenario", config.Scenario, false, funcMap)...)
+	errs = append(errs, config.validateDefinitions("mix", config.Mix, true, funcMap)...)
+
+	return errs
+}
+
+// validateData validates the data source. In unique mode every dodo needs a row of its own,
+// so the data file must have at least as many rows as the maximum number of dodos.
+func (config *Config) validateData() []error {
+	var errs []error
+	mode := config.GetDataMode()
+	if !slices.Contains(SupportedDataModes, mode) {
+		errs = append(errs,
+			fmt.Errorf("unsupported data mode \"%s\" (supported modes: %s)",
+				mode, strings.Join(SupportedDataModes, ", "),
 			),
-		),
-	).GetFuncMap()
+		)
+	}
+	if config.Data.File == "" {
+		errs = append(errs, errors.New("data mode can only be used together with a data file"))
+	}
+	if len(config.Replay) > 0 {
+		errs = append(errs, errors.New("data file cannot be used together with requests file or access log"))
+	}
 
-	for _, header := range config.Headers {
+	if mode == DataModeUnique && len(config.DataRows) > 0 {
+		var dodosCount uint
+		if config.DodosCount != nil {
+			dodosCount = *config.DodosCount
+		}
+		if maxDodos := config.Stages.MaxDodos(); maxDodos > 0 {
+			dodosCount = maxDodos
+		}
+		if uint(len(config.DataRows)) < dodosCount {
+			errs = append(errs, fmt.Errorf(
+				"data file has %d rows, but unique mode needs a row for each of the %d dodos",
+				len(config.DataRows), dodosCount,
+			))
+		}
+	}
+	return errs
+}
+
+// GetDataMode returns the mode of the data source, or the default mode if it isn't set.
+func (config *Config) GetDataMode() string {
+	if config.Data == nil || config.Data.Mode == "" {
+		return DefaultDataMode
+	}
+	return config.Data.Mode
+}
+
+// validateDefinitions validates the request definitions of the scenario or the mix,
+// prefixing the errors with the given name and the index of the definition.
+// Weights can only be set if the definitions are weighted. The query parameters of
+// the definition URLs are moved to their params.
+func (config *Config) validateDefinitions(
+	name string,
+	definitions types.RequestDefinitions,
+	weighted bool,
+	funcMap template.FuncMap,
+) []error {
+	var errs []error
+	for i := range definitions {
+		definition := &definitions[i]
+		prefix := fmt.Sprintf("%s[%d]", name, i)
+
+		if definition.URL == nil || !definition.URL.IsAbs() {
+			if utils.IsNilOrZero(config.URL) {
+				errs = append(errs, fmt.Errorf("%s: url is required when there is no top-level url to resolve it against", prefix))
+			}
+		} else if definition.URL.Scheme != "http" && definition.URL.Scheme != "https" {
+			errs = append(errs, fmt.Errorf("%s: request URL scheme must be http or https", prefix))
+		}
+		if definition.URL != nil {
+			definition.Params = append(urlParams(definition.URL.URL), definition.Params...)
+			definition.URL.RawQuery = ""
+		}
+
+		switch {
+		case definition.Weight == nil:
+		case !weighted:
+			errs = append(errs, fmt.Errorf("%s: weight can only be used in mix", prefix))
+		case *definition.Weight == 0:
+			errs = append(errs, fmt.Errorf("%s: weight must be greater than 0", prefix))
+		}
+
+		errs = append(errs, validateChecks(prefix+".checks", definition.Checks)...)
+		errs = append(errs, validateExtractors(prefix+".extract", definition.Extract)...)
+		for _, err := range validateTemplates(
+			funcMap, definition.Vars, definition.Params, definition.Headers, definition.Cookies, definition.Body,
+		) {
+			errs = append(errs, fmt.Errorf("%s: %v", prefix, err))
+		}
+	}
+	return errs
+}
+
+// urlParams returns the query parameters of the URL as request params, in the order their keys
+// first appear in the query, with the values of repeated keys kept together. Every value is a param
+// of its own, since the values of a param are picked randomly. Like URL.Query, invalid pairs are left out.
+func urlParams(URL url.URL) types.Params {
+	var (
+		keys   []string
+		values = make(map[string][]string)
+	)
+	// The pairs are split by hand instead of with URL.Query to keep their order.
+	for pair := range strings.SplitSeq(URL.RawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(pair, "=")
+		key, keyErr := url.QueryUnescape(key)
+		value, valueErr := url.QueryUnescape(value)
+		if keyErr != nil || valueErr != nil {
+			continue
+		}
+		if _, ok := values[key]; !ok {
+			keys = append(keys, key)
+		}
+		values[key] = append(values[key], value)
+	}
+
+	params := types.Params{}
+	for _, key := range keys {
+		for _, value := range values[key] {
+			params = append(params, types.KeyValue[string, []string]{
+				Key:   key,
+				Value: []string{value},
+			})
+		}
+	}
+	return params
+}
+
+// validateChecks validates the checks, prefixing the errors with the given name and the index of the check.
+func validateChecks(name string, checks types.Checks) []error {
+	var errs []error
+	for i, check := range checks {
+		switch kinds := check.Kinds(); {
+		case kinds == 0:
+			errs = append(errs, fmt.Errorf("%s[%d]: one of status, header, body_contains, body_regex, json_path or max_body_size is required", name, i))
+		case kinds > 1:
+			errs = append(errs, fmt.Errorf("%s[%d]: only one of status, header, body_contains, body_regex, json_path or max_body_size can be set", name, i))
+		case check.Equals != nil && check.Header == "" && check.JSONPath == "":
+			errs = append(errs, fmt.Errorf("%s[%d]: equals can only be used together with header or json_path", name, i))
+		}
+		if check.BodyRegex != "" {
+			if _, err := regexp.Compile(check.BodyRegex); err != nil {
+				errs = append(errs, fmt.Errorf("%s[%d]: invalid body_regex: %v", name, i, err))
+			}
+		}
+	}
+	return errs
+}
+
+// validateExtractors validates the extractors, prefixing the errors with the given name and the index of the extractor.
+func validateExtractors(name string, extractors types.Extractors) []error {
+	var errs []error
+	for i, extractor := range extractors {
+		if extractor.Var == "" {
+			errs = append(errs, fmt.Errorf("%s[%d]: var is required", name, i))
+		}
+		switch kinds := extractor.Kinds(); {
+		case kinds == 0:
+			errs = append(errs, fmt.Errorf("%s[%d]: one of json_path, regex, header or cookie is required", name, i))
+		case kinds > 1:
+			errs = append(errs, fmt.Errorf("%s[%d]: only one of json_path, regex, header or cookie can be set", name, i))
+		}
+		if extractor.Regex != "" {
+			if _, err := regexp.Compile(extractor.Regex); err != nil {
+				errs = append(errs, fmt.Errorf("%s[%d]: invalid regex: %v", name, i, err))
+			}
+		}
+	}
+	return errs
+}
+
+// validateTemplates checks that the templates of the vars, params, headers, cookies and body
+// of a request can be parsed and executed with the given template functions and
+// the template data of a dodo without any variables. The names of the vars must not be empty.
+func validateTemplates(
+	funcMap template.FuncMap,
+	vars types.Vars,
+	params types.Params,
+	headers types.Headers,
+	cookies types.Cookies,
+	body types.Body,
+) []error {
+	var (
+		errs []error
+		data = utils.NewTemplateData()
+	)
+
+	for _, variable := range vars {
+		if variable.Key == "" {
+			errs = append(errs, errors.New("var name cannot be empty"))
+		}
+
+		for _, value := range variable.Value {
+			t, err := template.New("default").Funcs(funcMap).Parse(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("var value (%s) parse error: %v", value, err))
+			} else {
+				var buf bytes.Buffer
+				if err = t.Execute(&buf, data); err != nil {
+					errs = append(errs, fmt.Errorf("var value (%s) parse error: %v", value, err))
+				}
+			}
+		}
+	}
+
+	for _, header := range headers {
 		t, err := template.New("default").Funcs(funcMap).Parse(header.Key)
 		if err != nil {
 			errs = append(errs, fmt.Errorf("header key (%s) parse error: %v", header.Key, err))
 		} else {
 			var buf bytes.Buffer
-			if err = t.Execute(&buf, nil); err != nil {
+			if err = t.Execute(&buf, data); err != nil {
 				errs = append(errs, fmt.Errorf("header key (%s) parse error: %v", header.Key, err))
 			}
 		}
@@ -226,20 +851,20 @@ func (config *Config) Validate() []error {
 				errs = append(errs, fmt.Errorf("header value (%s) parse error: %v", value, err))
 			} else {
 				var buf bytes.Buffer
-				if err = t.Execute(&buf, nil); err != nil {
+				if err = t.Execute(&buf, data); err != nil {
 					errs = append(errs, fmt.Errorf("header value (%s) parse error: %v", value, err))
 				}
 			}
 		}
 	}
 
-	for _, cookie := range config.Cookies {
+	for _, cookie := range cookies {
 		t, err := template.New("default").Funcs(funcMap).Parse(cookie.Key)
 		if err != nil {
 			errs = append(errs, fmt.Errorf("cookie key (%s) parse error: %v", cookie.Key, err))
 		} else {
 			var buf bytes.Buffer
-			if err = t.Execute(&buf, nil); err != nil {
+			if err = t.Execute(&buf, data); err != nil {
 				errs = append(errs, fmt.Errorf("cookie key (%s) parse error: %v", cookie.Key, err))
 			}
 		}
@@ -250,20 +875,20 @@ func (config *Config) Validate() []error {
 				errs = append(errs, fmt.Errorf("cookie value (%s) parse error: %v", value, err))
 			} else {
 				var buf bytes.Buffer
-				if err = t.Execute(&buf, nil); err != nil {
+				if err = t.Execute(&buf, data); err != nil {
 					errs = append(errs, fmt.Errorf("cookie value (%s) parse error: %v", value, err))
 				}
 			}
 		}
 	}
 
-	for _, param := range config.Params {
+	for _, param := range params {
 		t, err := template.New("default").Funcs(funcMap).Parse(param.Key)
 		if err != nil {
 			errs = append(errs, fmt.Errorf("param key (%s) parse error: %v", param.Key, err))
 		} else {
 			var buf bytes.Buffer
-			if err = t.Execute(&buf, nil); err != nil {
+			if err = t.Execute(&buf, data); err != nil {
 				errs = append(errs, fmt.Errorf("param key (%s) parse error: %v", param.Key, err))
 			}
 		}
@@ -274,20 +899,20 @@ func (config *Config) Validate() []error {
 				errs = append(errs, fmt.Errorf("param value (%s) parse error: %v", value, err))
 			} else {
 				var buf bytes.Buffer
-				if err = t.Execute(&buf, nil); err != nil {
+				if err = t.Execute(&buf, data); err != nil {
 					errs = append(errs, fmt.Errorf("param value (%s) parse error: %v", value, err))
 				}
 			}
 		}
 	}
 
-	for _, body := range config.Body {
+	for _, body := range body {
 		t, err := template.New("default").Funcs(funcMap).Parse(body)
 		if err != nil {
 			errs = append(errs, fmt.Errorf("body (%s) parse error: %v", body, err))
 		} else {
 			var buf bytes.Buffer
-			if err = t.Execute(&buf, nil); err != nil {
+			if err = t.Execute(&buf, data); err != nil {
 				errs = append(errs, fmt.Errorf("body (%s) parse error: %v", body, err))
 			}
 		}
@@ -296,6 +921,24 @@ func (config *Config) Validate() []error {
 	return errs
 }
 
+// escapeTemplate escapes the template actions in an imported value, such as the ones of curl commands
+// and HAR files, so that it is sent as it is even though the values of requests are templates.
+func escapeTemplate(value string) string {
+	return strings.ReplaceAll(value, "{{", `{{"{{"}}`)
+}
+
+// escapeKeyValueTemplates escapes the template actions in the keys and values of the imported key-value pairs.
+func escapeKeyValueTemplates(keyValues ...[]types.KeyValue[string, []string]) {
+	for _, items := range keyValues {
+		for i := range items {
+			items[i].Key = escapeTemplate(items[i].Key)
+			for j := range items[i].Value {
+				items[i].Value[j] = escapeTemplate(items[i].Value[j])
+			}
+		}
+	}
+}
+
 func (config *Config) MergeConfig(newConfig *Config) {
 	if newConfig.Method != nil {
 		config.Method = newConfig.Method
@@ -315,12 +958,48 @@ func (config *Config) MergeConfig(newConfig *Config) {
 	if newConfig.Duration != nil {
 		config.Duration = newConfig.Duration
 	}
+	if newConfig.Rate != nil {
+		config.Rate = newConfig.Rate
+	}
+	if len(newConfig.Stages) != 0 {
+		config.Stages = newConfig.Stages
+	}
+	if newConfig.Arrival != nil {
+		config.Arrival = newConfig.Arrival
+	}
+	if newConfig.HistogramSF != nil {
+		config.HistogramSF = newConfig.HistogramSF
+	}
+	if len(newConfig.Percentiles) != 0 {
+		config.Percentiles = newConfig.Percentiles
+	}
+	if newConfig.Interval != nil {
+		config.Interval = newConfig.Interval
+	}
+	if newConfig.Seed != nil {
+		config.Seed = newConfig.Seed
+	}
+	if newConfig.Output != nil {
+		config.Output = newConfig.Output
+	}
+	if newConfig.OutputFile != nil {
+		config.OutputFile = newConfig.OutputFile
+	}
+	if newConfig.CSVFile != nil {
+		config.CSVFile = newConfig.CSVFile
+	}
+	if len(newConfig.Thresholds) != 0 {
+		config.Thresholds = newConfig.Thresholds
+	}
 	if newConfig.Yes != nil {
 		config.Yes = newConfig.Yes
 	}
 	if newConfig.SkipVerify != nil {
 		config.SkipVerify = newConfig.SkipVerify
 	}
+	if len(newConfig.Vars) != 0 {
+		config.Vars = newConfig.Vars
+	}
 	if len(newConfig.Params) != 0 {
 		config.Params = newConfig.Params
 	}
@@ -336,6 +1015,71 @@ func (config *Config) MergeConfig(newConfig *Config) {
 	if len(newConfig.Proxies) != 0 {
 		config.Proxies = newConfig.Proxies
 	}
+	if len(newConfig.Checks) != 0 {
+		config.Checks = newConfig.Checks
+	}
+	if len(newConfig.Extract) != 0 {
+		config.Extract = newConfig.Extract
+	}
+	if len(newConfig.Scenario) != 0 {
+		config.Scenario = newConfig.Scenario
+	}
+	if len(newConfig.Mix) != 0 {
+		config.Mix = newConfig.Mix
+	}
+	if newConfig.RequestsFile != nil {
+		config.RequestsFile = newConfig.RequestsFile
+	}
+	if newConfig.RequestsOrder != nil {
+		config.RequestsOrder = newConfig.RequestsOrder
+	}
+	if newConfig.HAR != nil {
+		config.HAR = newConfig.HAR
+	}
+	if len(newConfig.HARHosts) != 0 {
+		config.HARHosts = newConfig.HARHosts
+	}
+	if len(newConfig.HARTypes) != 0 {
+		config.HARTypes = newConfig.HARTypes
+	}
+	if newConfig.Data != nil {
+		if config.Data == nil {
+			config.Data = &types.DataSource{}
+		}
+		if newConfig.Data.File != "" {
+			config.Data.File = newConfig.Data.File
+		}
+		if newConfig.Data.Mode != "" {
+			config.Data.Mode = newConfig.Data.Mode
+		}
+	}
+	if newConfig.AccessLog != nil {
+		config.AccessLog = newConfig.AccessLog
+	}
+	if newConfig.AccessLogSpeed != nil {
+		config.AccessLogSpeed = newConfig.AccessLogSpeed
+	}
+	if newConfig.OpenAPI != nil {
+		config.OpenAPI = newConfig.OpenAPI
+	}
+	if len(newConfig.OpenAPIOperations) != 0 {
+		config.OpenAPIOperations = newConfig.OpenAPIOperations
+	}
+	if newConfig.Curl != nil {
+		config.Curl = newConfig.Curl
+	}
+	if newConfig.ExportConfig != nil {
+		config.ExportConfig = newConfig.ExportConfig
+	}
+}
+
+// newRand returns a random number generator seeded with the seed of the config,
+// or with the current time if no seed is set yet.
+func (config *Config) newRand() *rand.Rand {
+	if config.Seed == nil {
+		return rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return rand.New(rand.NewSource(*config.Seed))
 }
 
 func (config *Config) SetDefaults() {
@@ -354,6 +1098,46 @@ func (config *Config) SetDefaults() {
 	if config.Duration == nil {
 		config.Duration = &types.Duration{Duration: DefaultDuration}
 	}
+	if config.Rate == nil {
+		config.Rate = utils.ToPtr(DefaultRate)
+	}
+	if config.Arrival == nil {
+		config.Arrival = utils.ToPtr(DefaultArrival)
+	}
+	if config.HistogramSF == nil {
+		config.HistogramSF = utils.ToPtr(DefaultHistogramSF)
+	}
+	if len(config.Percentiles) == 0 {
+		config.Percentiles = DefaultPercentiles
+	}
+	if config.Interval == nil {
+		config.Interval = &types.Duration{Duration: DefaultInterval}
+	}
+	if config.Seed == nil {
+		// A random seed is picked for every run, so that a run can be repeated with the printed seed.
+		config.Seed = utils.ToPtr(time.Now().UnixNano())
+	}
+	if config.Output == nil {
+		config.Output = utils.ToPtr(DefaultOutput)
+	}
+	if config.OutputFile == nil {
+		config.OutputFile = utils.ToPtr(DefaultOutputFile)
+	}
+	if config.CSVFile == nil {
+		config.CSVFile = utils.ToPtr(DefaultCSVFile)
+	}
+	if config.RequestsFile == nil {
+		config.RequestsFile = utils.ToPtr(DefaultRequestsFile)
+	}
+	if config.RequestsOrder == nil {
+		config.RequestsOrder = utils.ToPtr(DefaultRequestsOrder)
+	}
+	if config.AccessLog == nil {
+		config.AccessLog = utils.ToPtr(DefaultAccessLog)
+	}
+	if config.AccessLogSpeed == nil {
+		config.AccessLogSpeed = utils.ToPtr(DefaultAccessLogSpeed)
+	}
 	if config.Yes == nil {
 		config.Yes = utils.ToPtr(DefaultYes)
 	}
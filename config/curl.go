@@ -0,0 +1,401 @@
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aykhans/dodo/types"
+	"github.com/aykhans/dodo/utils"
+)
+
+// curlIgnoredOptions are the curl options without a value that don't change the request,
+// such as the ones added by "Copy as cURL" in browsers.
+var curlIgnoredOptions = map[string]bool{
+	"-s": true, "--silent": true,
+	"-S": true, "--show-error": true,
+	"-v": true, "--verbose": true,
+	"-i": true, "--include": true,
+	"-L": true, "--location": true,
+	"-f": true, "--fail": true,
+	"--compressed": true,
+	"--http1.1":    true,
+	"--http2":      true,
+}
+
+// curlValueOptions are the curl options that take a value.
+var curlValueOptions = map[string]bool{
+	"-X": true, "--request": true,
+	"-H": true, "--header": true,
+	"-d": true, "--data": true, "--data-raw": true, "--data-binary": true, "--data-ascii": true, "--data-urlencode": true,
+	"-F": true, "--form": true,
+	"-b": true, "--cookie": true,
+	"-x": true, "--proxy": true,
+	"-u": true, "--user": true,
+	"-A": true, "--user-agent": true,
+	"-e": true, "--referer": true,
+	"-m": true, "--max-time": true, "--url": true,
+}
+
+// ParseCurl parses a curl command line, such as the ones copied with "Copy as cURL" in browsers,
+// into a config with its URL, method, headers, cookies, body, proxy and TLS verification.
+// Like curl, the method is POST if data is given and GET otherwise, unless it is set with -X.
+func ParseCurl(command string) (*Config, error) {
+	args, err := splitShellWords(command)
+	if err != nil {
+		return nil, fmt.Errorf("curl command: %v", err)
+	}
+	if len(args) > 0 && args[0] == "curl" {
+		args = args[1:]
+	}
+
+	var (
+		config   = NewConfig()
+		data     []string
+		form     []string
+		getQuery bool
+	)
+	for i := 0; i < len(args); i++ {
+		option, value := args[i], ""
+
+		if !strings.HasPrefix(option, "-") || option == "-" {
+			if err := config.setCurlURL(option); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// Short options can be given together (e.g. -sSL) or with their value attached (e.g. -XPOST).
+		if !strings.HasPrefix(option, "--") && len(option) > 2 {
+			if curlValueOptions[option[:2]] {
+				option, value = option[:2], option[2:]
+			} else {
+				for _, flag := range option[1:] {
+					if !curlIgnoredOptions["-"+string(flag)] && flag != 'k' && flag != 'G' {
+						return nil, fmt.Errorf("curl command: unsupported option %s", option)
+					}
+					switch flag {
+					case 'k':
+						config.SkipVerify = utils.ToPtr(true)
+					case 'G':
+						getQuery = true
+					}
+				}
+				continue
+			}
+		} else if curlValueOptions[option] {
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("curl command: option %s requires a value", option)
+			}
+			i++
+			value = args[i]
+		}
+
+		switch option {
+		case "--url":
+			if err := config.setCurlURL(value); err != nil {
+				return nil, err
+			}
+		case "-X", "--request":
+			config.Method = utils.ToPtr(strings.ToUpper(value))
+		case "-H", "--header":
+			key, headerValue, _ := strings.Cut(value, ":")
+			key, headerValue = strings.TrimSpace(key), strings.TrimSpace(headerValue)
+			if strings.EqualFold(key, "Cookie") {
+				addCurlCookies(&config.Cookies, headerValue)
+			} else {
+				config.Headers.AppendByKey(key, headerValue)
+			}
+		case "-A", "--user-agent":
+			config.Headers.AppendByKey("User-Agent", value)
+		case "-e", "--referer":
+			config.Headers.AppendByKey("Referer", value)
+		case "-u", "--user":
+			config.Headers.AppendByKey("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(value)))
+		case "-b", "--cookie":
+			if !strings.Contains(value, "=") {
+				return nil, errors.New("curl command: reading cookies from a file is not supported")
+			}
+			addCurlCookies(&config.Cookies, value)
+		case "-d", "--data", "--data-ascii", "--data-binary", "--data-raw", "--data-urlencode":
+			item, err := curlData(option, value)
+			if err != nil {
+				return nil, err
+			}
+			data = append(data, item)
+		case "-F", "--form":
+			key, formValue, ok := strings.Cut(value, "=")
+			if !ok {
+				return nil, fmt.Errorf("curl command: invalid form field %q", value)
+			}
+			if strings.HasPrefix(formValue, "@") || strings.HasPrefix(formValue, "<") {
+				return nil, errors.New("curl command: form fields read from files are not supported")
+			}
+			form = append(form, strconv.Quote(key), strconv.Quote(formValue))
+		case "-x", "--proxy":
+			if !strings.Contains(value, "://") {
+				// curl defaults to http for proxies without a scheme.
+				value = "http://" + value
+			}
+			proxy, err := url.Parse(value)
+			if err != nil {
+				return nil, fmt.Errorf("curl command: invalid proxy %q", value)
+			}
+			config.Proxies = append(config.Proxies, *proxy)
+		case "-k", "--insecure":
+			config.SkipVerify = utils.ToPtr(true)
+		case "-G", "--get":
+			getQuery = true
+		case "-m", "--max-time":
+			seconds, err := strconv.ParseFloat(value, 64)
+			if err != nil || seconds <= 0 {
+				return nil, fmt.Errorf("curl command: invalid max time %q", value)
+			}
+			config.Timeout = &types.Timeout{Duration: time.Duration(seconds * float64(time.Second))}
+		default:
+			if !curlIgnoredOptions[option] {
+				return nil, fmt.Errorf("curl command: unsupported option %s", option)
+			}
+		}
+	}
+
+	if config.URL == nil {
+		return nil, errors.New("curl command: URL is required")
+	}
+	if len(data) > 0 && len(form) > 0 {
+		return nil, errors.New("curl command: data and form fields cannot be used together")
+	}
+
+	switch {
+	case getQuery:
+		// The pairs are split by hand instead of with url.ParseQuery to keep their order.
+		for pair := range strings.SplitSeq(strings.Join(data, "&"), "&") {
+			if pair == "" {
+				continue
+			}
+			key, value, _ := strings.Cut(pair, "=")
+			key, keyErr := url.QueryUnescape(key)
+			value, valueErr := url.QueryUnescape(value)
+			if keyErr != nil || valueErr != nil {
+				return nil, fmt.Errorf("curl command: invalid query data %q", pair)
+			}
+			config.Params.AppendByKey(key, value)
+		}
+	case len(data) > 0:
+		config.Body = types.Body{escapeTemplate(strings.Join(data, "&"))}
+		if !hasCurlHeader(config.Headers, "Content-Type") {
+			config.Headers.AppendByKey("Content-Type", "application/x-www-form-urlencoded")
+		}
+		if config.Method == nil {
+			config.Method = utils.ToPtr("POST")
+		}
+	case len(form) > 0:
+		// The multipart body and its Content-Type header are generated by the body_FormData template function,
+		// with the fields in the order they were given.
+		config.Body = types.Body{"{{ body_FormData (slice_Str " + strings.Join(form, " ") + ") }}"}
+		if config.Method == nil {
+			config.Method = utils.ToPtr("POST")
+		}
+	}
+
+	escapeKeyValueTemplates(config.Params, config.Headers, config.Cookies)
+
+	return config, nil
+}
+
+func (config *Config) setCurlURL(value string) error {
+	if config.URL != nil {
+		return errors.New("curl command: only one URL is supported")
+	}
+	if !strings.Contains(value, "://") {
+		// curl defaults to http for URLs without a scheme.
+		value = "http://" + value
+	}
+	var requestURL types.RequestURL
+	if err := requestURL.Set(value); err != nil {
+		return fmt.Errorf("curl command: invalid URL %q", value)
+	}
+	config.URL = &requestURL
+	return nil
+}
+
+// hasCurlHeader reports whether the header is set, ignoring the case of its name like HTTP.
+func hasCurlHeader(headers types.Headers, key string) bool {
+	for _, header := range headers {
+		if strings.EqualFold(header.Key, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// addCurlCookies adds the cookies of a "name1=value1; name2=value2" cookie string.
+func addCurlCookies(cookies *types.Cookies, value string) {
+	for cookie := range strings.SplitSeq(value, ";") {
+		name, cookieValue, _ := strings.Cut(strings.TrimSpace(cookie), "=")
+		if name != "" {
+			cookies.AppendByKey(name, cookieValue)
+		}
+	}
+}
+
+// curlData returns the data of a curl data option as it is sent in the body.
+// Like curl, data starting with @ is read from the file, except for --data-raw, and
+// --data-urlencode encodes the value of "name=value" or the whole content.
+func curlData(option, value string) (string, error) {
+	if option == "--data-urlencode" {
+		// Like curl, the value is read from a file if it has an @ before any =, as in "@file" or "name@file".
+		if i := strings.IndexAny(value, "@="); i >= 0 && value[i] == '@' {
+			return "", errors.New("curl command: --data-urlencode data read from files is not supported")
+		}
+		name, content, ok := strings.Cut(value, "=")
+		if !ok {
+			return url.QueryEscape(value), nil
+		}
+		if name == "" {
+			return url.QueryEscape(content), nil
+		}
+		return name + "=" + url.QueryEscape(content), nil
+	}
+
+	if option != "--data-raw" && strings.HasPrefix(value, "@") {
+		content, err := os.ReadFile(value[1:])
+		if err != nil {
+			return "", fmt.Errorf("curl command: failed to read data from %s", value[1:])
+		}
+		if option != "--data-binary" {
+			// Like curl, newlines of the file are stripped unless it is sent as binary.
+			content = []byte(strings.NewReplacer("\r", "", "\n", "").Replace(string(content)))
+		}
+		return string(content), nil
+	}
+	return value, nil
+}
+
+// splitShellWords splits a command line into its arguments like a POSIX shell, supporting
+// single quotes, double quotes, ANSI-C quotes ($'...'), backslash escapes and line continuations.
+func splitShellWords(command string) ([]string, error) {
+	var (
+		words   []string
+		word    strings.Builder
+		inWord  bool
+		runes   = []rune(command)
+		runeLen = len(runes)
+	)
+	for i := 0; i < runeLen; i++ {
+		r := runes[i]
+		switch {
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+
+		case r == '\\':
+			if i+1 < runeLen {
+				i++
+				if runes[i] != '\n' {
+					word.WriteRune(runes[i])
+					inWord = true
+				}
+			}
+
+		case r == '\'':
+			end := indexRune(runes, '\'', i+1)
+			if end < 0 {
+				return nil, errors.New("unterminated single quote")
+			}
+			word.WriteString(string(runes[i+1 : end]))
+			i, inWord = end, true
+
+		case r == '$' && i+1 < runeLen && runes[i+1] == '\'':
+			end, err := writeANSICQuoted(&word, runes, i+2)
+			if err != nil {
+				return nil, err
+			}
+			i, inWord = end, true
+
+		case r == '"':
+			i++
+			for ; i < runeLen && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < runeLen && strings.ContainsRune("\"\\$`\n", runes[i+1]) {
+					i++
+					if runes[i] == '\n' {
+						continue
+					}
+				}
+				word.WriteRune(runes[i])
+			}
+			if i >= runeLen {
+				return nil, errors.New("unterminated double quote")
+			}
+			inWord = true
+
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+func indexRune(runes []rune, r rune, from int) int {
+	for i := from; i < len(runes); i++ {
+		if runes[i] == r {
+			return i
+		}
+	}
+	return -1
+}
+
+// writeANSICQuoted writes the content of an ANSI-C quoted string starting at the given index,
+// right after the opening $', and returns the index of the closing quote.
+func writeANSICQuoted(word *strings.Builder, runes []rune, from int) (int, error) {
+	for i := from; i < len(runes); i++ {
+		switch r := runes[i]; {
+		case r == '\'':
+			return i, nil
+		case r == '\\' && i+1 < len(runes):
+			i++
+			switch escaped := runes[i]; escaped {
+			case 'n':
+				word.WriteByte('\n')
+			case 't':
+				word.WriteByte('\t')
+			case 'r':
+				word.WriteByte('\r')
+			case 'x', 'u', 'U':
+				digits := map[rune]int{'x': 2, 'u': 4, 'U': 8}[escaped]
+				end := i + 1
+				for end < len(runes) && end < i+1+digits && strings.ContainsRune("0123456789abcdefABCDEF", runes[end]) {
+					end++
+				}
+				code, err := strconv.ParseUint(string(runes[i+1:end]), 16, 32)
+				if err != nil {
+					return 0, fmt.Errorf("invalid escape sequence \\%c", escaped)
+				}
+				if escaped == 'x' {
+					word.WriteByte(byte(code))
+				} else if utf8.ValidRune(rune(code)) {
+					word.WriteRune(rune(code))
+				}
+				i = end - 1
+			default:
+				word.WriteRune(escaped)
+			}
+		default:
+			word.WriteRune(r)
+		}
+	}
+	return 0, errors.New("unterminated ANSI-C quote")
+}
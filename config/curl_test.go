@@ -0,0 +1,292 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aykhans/dodo/types"
+)
+
+func TestSplitShellWords(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		want    []string
+		wantErr string
+	}{
+		{name: "empty", command: "", want: nil},
+		{name: "spaces", command: "  curl \t http://a  ", want: []string{"curl", "http://a"}},
+		{name: "single quotes", command: `curl -H 'X-A: "b" \n'`, want: []string{"curl", "-H", `X-A: "b" \n`}},
+		{name: "double quotes", command: "curl -d \"{\\\"a\\\": \\$b\\`c\\\\}\"", want: []string{"curl", "-d", "{\"a\": $b`c\\}"}},
+		{name: "double quotes keep other backslashes", command: `"a\b"`, want: []string{`a\b`}},
+		{name: "backslash escapes", command: `a\ b c\'d`, want: []string{"a b", "c'd"}},
+		{name: "line continuations", command: "curl \\\n  -X POST \\\n  http://a", want: []string{"curl", "-X", "POST", "http://a"}},
+		{name: "adjacent quotes join", command: `a'b'"c"d`, want: []string{"abcd"}},
+		{name: "empty quotes", command: `a '' ""`, want: []string{"a", "", ""}},
+		{name: "ANSI-C quotes", command: `$'a\nb\t\'c\x41é'`, want: []string{"a\nb\t'cAé"}},
+		{name: "unterminated single quote", command: "curl 'a", wantErr: "unterminated single quote"},
+		{name: "unterminated double quote", command: `curl "a`, wantErr: "unterminated double quote"},
+		{name: "unterminated ANSI-C quote", command: `curl $'a`, wantErr: "unterminated ANSI-C quote"},
+		{name: "invalid ANSI-C escape", command: `$'\xzz'`, wantErr: `invalid escape sequence \x`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := splitShellWords(test.command)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("splitShellWords(%q) error = %v, want it to contain %q", test.command, err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("splitShellWords(%q) unexpected error: %v", test.command, err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("splitShellWords(%q) = %q, want %q", test.command, got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseCurlURLAndMethod(t *testing.T) {
+	tests := []struct {
+		command    string
+		wantURL    string
+		wantMethod string
+	}{
+		{command: "curl https://example.com/items?a=1", wantURL: "https://example.com/items?a=1"},
+		{command: "curl example.com", wantURL: "http://example.com"},
+		{command: "curl --url https://a -XPUT", wantURL: "https://a", wantMethod: "PUT"},
+		{command: "curl -X post https://a", wantURL: "https://a", wantMethod: "POST"},
+		// Like curl, data and form fields are POSTed unless the method is set, and -G sends the data as a query.
+		{command: "curl https://a -d a=1", wantURL: "https://a", wantMethod: "POST"},
+		{command: "curl https://a -F a=1", wantURL: "https://a", wantMethod: "POST"},
+		{command: "curl -X PUT https://a -d a=1", wantURL: "https://a", wantMethod: "PUT"},
+		{command: "curl -G https://a -d a=1", wantURL: "https://a"},
+	}
+
+	for _, test := range tests {
+		config, err := ParseCurl(test.command)
+		if err != nil {
+			t.Errorf("ParseCurl(%q) unexpected error: %v", test.command, err)
+			continue
+		}
+		if got := config.URL.String(); got != test.wantURL {
+			t.Errorf("ParseCurl(%q) URL = %q, want %q", test.command, got, test.wantURL)
+		}
+		var method string
+		if config.Method != nil {
+			method = *config.Method
+		}
+		if method != test.wantMethod {
+			t.Errorf("ParseCurl(%q) method = %q, want %q", test.command, method, test.wantMethod)
+		}
+	}
+}
+
+func TestParseCurlHeadersAndCookies(t *testing.T) {
+	tests := []struct {
+		name        string
+		command     string
+		wantHeaders types.Headers
+		wantCookies types.Cookies
+	}{
+		{
+			name:        "header values are trimmed",
+			command:     `curl https://a -H 'Accept: application/json' --header "X-Id:  1 " -H 'X-Url: http://b'`,
+			wantHeaders: types.Headers{{Key: "Accept", Value: []string{"application/json"}}, {Key: "X-Id", Value: []string{"1"}}, {Key: "X-Url", Value: []string{"http://b"}}},
+		},
+		{
+			name:        "cookie header and option",
+			command:     `curl https://a -H 'cookie: a=1; b=2' -b 'c=3;a=4'`,
+			wantCookies: types.Cookies{{Key: "a", Value: []string{"1", "4"}}, {Key: "b", Value: []string{"2"}}, {Key: "c", Value: []string{"3"}}},
+		},
+		{
+			name:    "user agent, referer and user",
+			command: `curl https://a -A agent -e https://ref -u user:pass`,
+			wantHeaders: types.Headers{
+				{Key: "User-Agent", Value: []string{"agent"}},
+				{Key: "Referer", Value: []string{"https://ref"}},
+				{Key: "Authorization", Value: []string{"Basic dXNlcjpwYXNz"}},
+			},
+		},
+		{
+			name:        "data adds a form content type",
+			command:     `curl https://a -d a=1`,
+			wantHeaders: types.Headers{{Key: "Content-Type", Value: []string{"application/x-www-form-urlencoded"}}},
+		},
+		{
+			name:        "data keeps the given content type",
+			command:     `curl https://a -H 'content-type: application/json' -d '{}'`,
+			wantHeaders: types.Headers{{Key: "content-type", Value: []string{"application/json"}}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config, err := ParseCurl(test.command)
+			if err != nil {
+				t.Fatalf("ParseCurl(%q) unexpected error: %v", test.command, err)
+			}
+			if !reflect.DeepEqual(config.Headers, test.wantHeaders) {
+				t.Errorf("ParseCurl(%q) headers = %v, want %v", test.command, config.Headers, test.wantHeaders)
+			}
+			if !reflect.DeepEqual(config.Cookies, test.wantCookies) {
+				t.Errorf("ParseCurl(%q) cookies = %v, want %v", test.command, config.Cookies, test.wantCookies)
+			}
+		})
+	}
+}
+
+func TestParseCurlData(t *testing.T) {
+	dataFile := filepath.Join(t.TempDir(), "data.txt")
+	if err := os.WriteFile(dataFile, []byte("a=1\r\nb=2\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		command    string
+		wantBody   types.Body
+		wantParams types.Params
+	}{
+		{
+			name:     "data options are joined",
+			command:  `curl https://a -d a=1 --data-raw '@b=2' --data-ascii c=3`,
+			wantBody: types.Body{"a=1&@b=2&c=3"},
+		},
+		{
+			name:     "urlencoded data",
+			command:  `curl https://a --data-urlencode 'c=x y' --data-urlencode =z& --data-urlencode 'a b' --data-urlencode 'mail=a@b.c'`,
+			wantBody: types.Body{"c=x+y&z%26&a+b&mail=a%40b.c"},
+		},
+		{
+			name:     "data from file drops newlines",
+			command:  "curl https://a -d @" + dataFile,
+			wantBody: types.Body{"a=1b=2"},
+		},
+		{
+			name:     "binary data from file keeps newlines",
+			command:  "curl https://a --data-binary @" + dataFile,
+			wantBody: types.Body{"a=1\r\nb=2\n"},
+		},
+		{
+			name:    "data as query",
+			command: `curl -G https://a -d 'q=a%20b&page=2' -d 'q=c' -d 'flag'`,
+			wantParams: types.Params{
+				{Key: "q", Value: []string{"a b", "c"}},
+				{Key: "page", Value: []string{"2"}},
+				{Key: "flag", Value: []string{""}},
+			},
+		},
+		{
+			name:     "form fields keep their order",
+			command:  `curl https://a -F z=1 -F 'a=x "y"' -F z=3`,
+			wantBody: types.Body{`{{ body_FormData (slice_Str "z" "1" "a" "x \"y\"" "z" "3") }}`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config, err := ParseCurl(test.command)
+			if err != nil {
+				t.Fatalf("ParseCurl(%q) unexpected error: %v", test.command, err)
+			}
+			if !reflect.DeepEqual(config.Body, test.wantBody) {
+				t.Errorf("ParseCurl(%q) body = %q, want %q", test.command, config.Body, test.wantBody)
+			}
+			if !reflect.DeepEqual(config.Params, test.wantParams) {
+				t.Errorf("ParseCurl(%q) params = %v, want %v", test.command, config.Params, test.wantParams)
+			}
+		})
+	}
+}
+
+func TestParseCurlEscapesTemplates(t *testing.T) {
+	// The values of curl commands are sent as they are, so template actions in them must not be run.
+	command := `curl https://a -H 'X-{{a}}: {{ .x }}' -b 'c={{x}}' --data-raw '{"a":"{{b}}"}'`
+	config, err := ParseCurl(command)
+	if err != nil {
+		t.Fatalf("ParseCurl(%q) unexpected error: %v", command, err)
+	}
+
+	wantHeaders := types.Headers{
+		{Key: `X-{{"{{"}}a}}`, Value: []string{`{{"{{"}} .x }}`}},
+		{Key: "Content-Type", Value: []string{"application/x-www-form-urlencoded"}},
+	}
+	if !reflect.DeepEqual(config.Headers, wantHeaders) {
+		t.Errorf("ParseCurl(%q) headers = %v, want %v", command, config.Headers, wantHeaders)
+	}
+	if wantCookies := (types.Cookies{{Key: "c", Value: []string{`{{"{{"}}x}}`}}}); !reflect.DeepEqual(config.Cookies, wantCookies) {
+		t.Errorf("ParseCurl(%q) cookies = %v, want %v", command, config.Cookies, wantCookies)
+	}
+	if wantBody := (types.Body{`{"a":"{{"{{"}}b}}"}`}); !reflect.DeepEqual(config.Body, wantBody) {
+		t.Errorf("ParseCurl(%q) body = %q, want %q", command, config.Body, wantBody)
+	}
+
+	command = `curl -G https://a -d 'q={{x}}'`
+	if config, err = ParseCurl(command); err != nil {
+		t.Fatalf("ParseCurl(%q) unexpected error: %v", command, err)
+	}
+	if wantParams := (types.Params{{Key: "q", Value: []string{`{{"{{"}}x}}`}}}); !reflect.DeepEqual(config.Params, wantParams) {
+		t.Errorf("ParseCurl(%q) params = %v, want %v", command, config.Params, wantParams)
+	}
+}
+
+func TestParseCurlOptions(t *testing.T) {
+	command := `curl -sSLk --compressed -x proxy:8080 --proxy socks5://p:1080 -m 2.5 https://a`
+	config, err := ParseCurl(command)
+	if err != nil {
+		t.Fatalf("ParseCurl(%q) unexpected error: %v", command, err)
+	}
+
+	var proxies []string
+	for _, proxy := range config.Proxies {
+		proxies = append(proxies, proxy.String())
+	}
+	if want := []string{"http://proxy:8080", "socks5://p:1080"}; !reflect.DeepEqual(proxies, want) {
+		t.Errorf("ParseCurl(%q) proxies = %q, want %q", command, proxies, want)
+	}
+	if config.SkipVerify == nil || !*config.SkipVerify {
+		t.Errorf("ParseCurl(%q) skip verify = %v, want true", command, config.SkipVerify)
+	}
+	if config.Timeout == nil || config.Timeout.Duration != 2500*time.Millisecond {
+		t.Errorf("ParseCurl(%q) timeout = %v, want 2.5s", command, config.Timeout)
+	}
+}
+
+func TestParseCurlErrors(t *testing.T) {
+	tests := []struct {
+		command string
+		wantErr string
+	}{
+		{command: "curl -X GET", wantErr: "URL is required"},
+		{command: "curl https://a https://b", wantErr: "only one URL is supported"},
+		{command: "curl https://a -H", wantErr: "option -H requires a value"},
+		{command: "curl https://a --retry 3", wantErr: "unsupported option --retry"},
+		{command: "curl -sZ https://a", wantErr: "unsupported option -sZ"},
+		{command: "curl https://a -d a=1 -F b=2", wantErr: "data and form fields cannot be used together"},
+		{command: "curl https://a -F b", wantErr: "invalid form field"},
+		{command: "curl https://a -F b=@file", wantErr: "form fields read from files are not supported"},
+		{command: "curl https://a -F 'b=<file'", wantErr: "form fields read from files are not supported"},
+		{command: "curl https://a --data-urlencode @file", wantErr: "--data-urlencode data read from files is not supported"},
+		{command: "curl https://a --data-urlencode name@file", wantErr: "--data-urlencode data read from files is not supported"},
+		{command: "curl https://a -b cookies.txt", wantErr: "reading cookies from a file is not supported"},
+		{command: "curl https://a -d @/nonexistent/file", wantErr: "failed to read data from /nonexistent/file"},
+		{command: "curl https://a -m 0", wantErr: "invalid max time"},
+		{command: "curl -G https://a -d 'q=%zz'", wantErr: "invalid query data"},
+		{command: "curl 'https://a", wantErr: "unterminated single quote"},
+	}
+
+	for _, test := range tests {
+		_, err := ParseCurl(test.command)
+		if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+			t.Errorf("ParseCurl(%q) error = %v, want it to contain %q", test.command, err, test.wantErr)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ReadData reads the rows of the configured data file, a CSV file with a header row or
+// a JSONL file with a JSON object on each line, into the rows the templates can use.
+// The columns of the CSV rows are named by the header row. Empty lines are skipped.
+// It does nothing if no data file is configured.
+func (config *Config) ReadData() error {
+	if config.Data == nil || config.Data.File == "" {
+		return nil
+	}
+
+	file, err := os.Open(config.Data.File)
+	if err != nil {
+		return errors.New("failed to read data file from " + config.Data.File)
+	}
+	defer func() { _ = file.Close() }()
+
+	var rows []map[string]any
+	switch extension := strings.ToLower(filepath.Ext(config.Data.File)); extension {
+	case ".csv":
+		rows, err = readCSVRows(file)
+	case ".jsonl", ".ndjson":
+		rows, err = readJSONLRows(file)
+	default:
+		return fmt.Errorf("data file must be a .csv or .jsonl file, got %q", extension)
+	}
+	if err != nil {
+		return fmt.Errorf("data file %s: %v", config.Data.File, err)
+	}
+	if len(rows) == 0 {
+		return errors.New("data file has no rows")
+	}
+
+	config.DataRows = rows
+	return nil
+}
+
+func readCSVRows(r io.Reader) ([]map[string]any, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []map[string]any
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]any, len(header))
+		for i, column := range header {
+			row[column] = record[i]
+		}
+		rows = append(rows, row)
+	}
+}
+
+func readJSONLRows(r io.Reader) ([]map[string]any, error) {
+	var (
+		scanner    = bufio.NewScanner(r)
+		lineNumber = 0
+		rows       []map[string]any
+	)
+	scanner.Buffer(nil, maxRequestsFileLineSize)
+	for scanner.Scan() {
+		lineNumber++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		// Numbers are kept as they are written, instead of being formatted as floats by the templates.
+		decoder := json.NewDecoder(bytes.NewReader(line))
+		decoder.UseNumber()
+		var row map[string]any
+		if err := decoder.Decode(&row); err != nil || row == nil {
+			return nil, fmt.Errorf("line %d: should be a JSON object", lineNumber)
+		}
+		rows = append(rows, row)
+	}
+	return rows, scanner.Err()
+}
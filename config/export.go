@@ -0,0 +1,94 @@
+package config
+
+import (
+	"io"
+
+	"github.com/aykhans/dodo/types"
+	"gopkg.in/yaml.v3"
+)
+
+// exportedConfig is the config written by WriteYAML. Only the fields that are set are written.
+// The HAR and OpenAPI imports are left out, since their requests are written instead:
+// the ones of the HAR file as the scenario and the ones of the OpenAPI spec as the mix.
+type exportedConfig struct {
+	Method         *string                  `yaml:"method,omitempty"`
+	URL            *types.RequestURL        `yaml:"url,omitempty"`
+	Timeout        *types.Timeout           `yaml:"timeout,omitempty"`
+	DodosCount     *uint                    `yaml:"dodos,omitempty"`
+	RequestCount   *uint                    `yaml:"requests,omitempty"`
+	Duration       *types.Duration          `yaml:"duration,omitempty"`
+	Rate           *uint                    `yaml:"rate,omitempty"`
+	Stages         types.Stages             `yaml:"stages,omitempty"`
+	Arrival        *string                  `yaml:"arrival,omitempty"`
+	HistogramSF    *uint                    `yaml:"histogram_precision,omitempty"`
+	Percentiles    types.Percentiles        `yaml:"percentiles,omitempty"`
+	Interval       *types.Duration          `yaml:"interval,omitempty"`
+	Seed           *int64                   `yaml:"seed,omitempty"`
+	Output         *string                  `yaml:"output,omitempty"`
+	OutputFile     *string                  `yaml:"output_file,omitempty"`
+	CSVFile        *string                  `yaml:"csv_file,omitempty"`
+	Thresholds     types.Thresholds         `yaml:"thresholds,omitempty"`
+	Yes            *bool                    `yaml:"yes,omitempty"`
+	SkipVerify     *bool                    `yaml:"skip_verify,omitempty"`
+	Vars           types.Vars               `yaml:"vars,omitempty"`
+	Params         types.Params             `yaml:"params,omitempty"`
+	Headers        types.Headers            `yaml:"headers,omitempty"`
+	Cookies        types.Cookies            `yaml:"cookies,omitempty"`
+	Body           types.Body               `yaml:"body,omitempty"`
+	Proxies        types.Proxies            `yaml:"proxy,omitempty"`
+	Checks         types.Checks             `yaml:"checks,omitempty"`
+	Extract        types.Extractors         `yaml:"extract,omitempty"`
+	Scenario       types.RequestDefinitions `yaml:"scenario,omitempty"`
+	Mix            types.RequestDefinitions `yaml:"mix,omitempty"`
+	RequestsFile   *string                  `yaml:"requests_file,omitempty"`
+	RequestsOrder  *string                  `yaml:"requests_order,omitempty"`
+	AccessLog      *string                  `yaml:"access_log,omitempty"`
+	AccessLogSpeed *float64                 `yaml:"access_log_speed,omitempty"`
+	Data           *types.DataSource        `yaml:"data,omitempty"`
+}
+
+// WriteYAML writes the fields of the config that are set, including the imported requests,
+// as a YAML config file that can be edited and run with -config-file.
+func (config *Config) WriteYAML(w io.Writer) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(4)
+	if err := encoder.Encode(exportedConfig{
+		Method:         config.Method,
+		URL:            config.URL,
+		Timeout:        config.Timeout,
+		DodosCount:     config.DodosCount,
+		RequestCount:   config.RequestCount,
+		Duration:       config.Duration,
+		Rate:           config.Rate,
+		Stages:         config.Stages,
+		Arrival:        config.Arrival,
+		HistogramSF:    config.HistogramSF,
+		Percentiles:    config.Percentiles,
+		Interval:       config.Interval,
+		Seed:           config.Seed,
+		Output:         config.Output,
+		OutputFile:     config.OutputFile,
+		CSVFile:        config.CSVFile,
+		Thresholds:     config.Thresholds,
+		Yes:            config.Yes,
+		SkipVerify:     config.SkipVerify,
+		Vars:           config.Vars,
+		Params:         config.Params,
+		Headers:        config.Headers,
+		Cookies:        config.Cookies,
+		Body:           config.Body,
+		Proxies:        config.Proxies,
+		Checks:         config.Checks,
+		Extract:        config.Extract,
+		Scenario:       config.Scenario,
+		Mix:            config.Mix,
+		RequestsFile:   config.RequestsFile,
+		RequestsOrder:  config.RequestsOrder,
+		AccessLog:      config.AccessLog,
+		AccessLogSpeed: config.AccessLogSpeed,
+		Data:           config.Data,
+	}); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
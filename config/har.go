@@ -0,0 +1,174 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/aykhans/dodo/types"
+)
+
+// harIgnoredHeaders are the request headers of a HAR file that are not imported, since they
+// are set from the URL, the cookies and the body of the request, or by the connection.
+var harIgnoredHeaders = []string{"host", "content-length", "cookie", "connection"}
+
+type harFile struct {
+	Log struct {
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	// ResourceType is the type of the resource recorded by Chromium based browsers,
+	// e.g. document, xhr, fetch, script, stylesheet, image or font.
+	ResourceType string     `json:"_resourceType"`
+	Request      harRequest `json:"request"`
+}
+
+type harRequest struct {
+	Method   string         `json:"method"`
+	URL      string         `json:"url"`
+	Headers  []harNameValue `json:"headers"`
+	Cookies  []harNameValue `json:"cookies"`
+	PostData *struct {
+		MimeType string         `json:"mimeType"`
+		Text     string         `json:"text"`
+		Params   []harNameValue `json:"params"`
+	} `json:"postData"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// ReadHAR imports the requests of the configured HAR file as the steps of the scenario,
+// in the order they were recorded. Only the requests to the configured hosts and of the
+// configured resource types are imported, if any are set.
+// It does nothing if no HAR file is configured.
+func (config *Config) ReadHAR() error {
+	if config.HAR == nil || *config.HAR == "" {
+		return nil
+	}
+	if len(config.Scenario) > 0 || len(config.Mix) > 0 {
+		return errors.New("HAR file cannot be used together with scenario or mix")
+	}
+
+	data, err := os.ReadFile(*config.HAR)
+	if err != nil {
+		return errors.New("failed to read HAR file from " + *config.HAR)
+	}
+
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil {
+		return fmt.Errorf("HAR file: %v", err)
+	}
+
+	var steps types.RequestDefinitions
+	for i, entry := range har.Log.Entries {
+		requestURL, err := url.Parse(entry.Request.URL)
+		if err != nil || (requestURL.Scheme != "http" && requestURL.Scheme != "https") {
+			// Entries such as data: and blob: URLs can't be sent.
+			continue
+		}
+		if !harMatchesHost(config.HARHosts, requestURL.Hostname()) ||
+			!harMatchesType(config.HARTypes, entry.ResourceType) {
+			continue
+		}
+
+		step, err := harRequestDefinition(entry.Request, requestURL)
+		if err != nil {
+			return fmt.Errorf("HAR file entries[%d]: %v", i, err)
+		}
+		steps = append(steps, step)
+	}
+	if len(steps) == 0 {
+		return errors.New("HAR file has no requests matching the filters")
+	}
+
+	config.Scenario = steps
+	return nil
+}
+
+// harMatchesHost reports whether the host is one of the hosts, or a subdomain of
+// a host given as "*.example.com". Every host matches if there are no hosts.
+func harMatchesHost(hosts []string, host string) bool {
+	if len(hosts) == 0 {
+		return true
+	}
+	for _, pattern := range hosts {
+		if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+		} else if strings.EqualFold(pattern, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// harMatchesType reports whether the resource type is one of the types.
+// Every resource type matches if there are no types.
+func harMatchesType(resourceTypes []string, resourceType string) bool {
+	if len(resourceTypes) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(resourceTypes, func(t string) bool {
+		return strings.EqualFold(t, resourceType)
+	})
+}
+
+func harRequestDefinition(request harRequest, requestURL *url.URL) (types.RequestDefinition, error) {
+	if request.Method == "" {
+		return types.RequestDefinition{}, errors.New("request method is required")
+	}
+
+	step := types.RequestDefinition{
+		Method: strings.ToUpper(request.Method),
+		Params: urlParams(*requestURL),
+	}
+	requestURL.RawQuery = ""
+	requestURL.Fragment = ""
+	step.URL = &types.RequestURL{URL: *requestURL}
+
+	for _, header := range request.Headers {
+		// HTTP/2 pseudo headers such as :authority are part of the request line.
+		if strings.HasPrefix(header.Name, ":") || slices.Contains(harIgnoredHeaders, strings.ToLower(header.Name)) {
+			continue
+		}
+		step.Headers = append(step.Headers, types.KeyValue[string, []string]{
+			Key:   header.Name,
+			Value: []string{header.Value},
+		})
+	}
+	for _, cookie := range request.Cookies {
+		step.Cookies = append(step.Cookies, types.KeyValue[string, []string]{
+			Key:   cookie.Name,
+			Value: []string{cookie.Value},
+		})
+	}
+	// The recorded values are sent as they are, so template actions in them must not be run.
+	escapeKeyValueTemplates(step.Params, step.Headers, step.Cookies)
+
+	if postData := request.PostData; postData != nil {
+		if postData.MimeType != "" {
+			step.Headers.SetIfNotExists("Content-Type", postData.MimeType)
+		}
+		switch {
+		case postData.Text != "":
+			step.Body = types.Body{escapeTemplate(postData.Text)}
+		case len(postData.Params) > 0:
+			form := url.Values{}
+			for _, param := range postData.Params {
+				form.Add(param.Name, param.Value)
+			}
+			step.Body = types.Body{escapeTemplate(form.Encode())}
+		}
+	}
+	return step, nil
+}
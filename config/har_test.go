@@ -0,0 +1,96 @@
+package config
+
+import (
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/aykhans/dodo/types"
+)
+
+func TestHARRequestDefinition(t *testing.T) {
+	tests := []struct {
+		name        string
+		request     string
+		wantURL     string
+		wantParams  types.Params
+		wantHeaders types.Headers
+		wantCookies types.Cookies
+		wantBody    types.Body
+	}{
+		{
+			name:       "query keeps its order",
+			request:    `{"method": "get", "url": "https://a/items?z=1&b=x%20y&z=2#top"}`,
+			wantURL:    "https://a/items",
+			wantParams: types.Params{{Key: "z", Value: []string{"1"}}, {Key: "z", Value: []string{"2"}}, {Key: "b", Value: []string{"x y"}}},
+		},
+		{
+			// Pseudo headers and the headers set by the connection are left out.
+			name: "headers and cookies",
+			request: `{"method": "GET", "url": "https://a/", "headers": [
+				{"name": ":authority", "value": "a"}, {"name": "Host", "value": "a"}, {"name": "Cookie", "value": "c=1"},
+				{"name": "Accept", "value": "*/*"}], "cookies": [{"name": "c", "value": "1"}]}`,
+			wantURL:     "https://a/",
+			wantParams:  types.Params{},
+			wantHeaders: types.Headers{{Key: "Accept", Value: []string{"*/*"}}},
+			wantCookies: types.Cookies{{Key: "c", Value: []string{"1"}}},
+		},
+		{
+			name: "form params",
+			request: `{"method": "POST", "url": "https://a/", "postData": {
+				"mimeType": "application/x-www-form-urlencoded", "params": [{"name": "b", "value": "x y"}, {"name": "a", "value": "1"}]}}`,
+			wantURL:     "https://a/",
+			wantParams:  types.Params{},
+			wantHeaders: types.Headers{{Key: "Content-Type", Value: []string{"application/x-www-form-urlencoded"}}},
+			wantBody:    types.Body{"a=1&b=x+y"},
+		},
+		{
+			// The recorded values are sent as they are, so the template actions in them are escaped.
+			name: "templates are escaped",
+			request: `{"method": "POST", "url": "https://a/?q={{x}}", "headers": [{"name": "X-{{a}}", "value": "{{ not a template"}],
+				"cookies": [{"name": "c", "value": "{{.c}}"}], "postData": {"mimeType": "application/json", "text": "{\"a\":\"{{b}}\"}"}}`,
+			wantURL:    "https://a/",
+			wantParams: types.Params{{Key: "q", Value: []string{`{{"{{"}}x}}`}}},
+			wantHeaders: types.Headers{
+				{Key: `X-{{"{{"}}a}}`, Value: []string{`{{"{{"}} not a template`}},
+				{Key: "Content-Type", Value: []string{"application/json"}},
+			},
+			wantCookies: types.Cookies{{Key: "c", Value: []string{`{{"{{"}}.c}}`}}},
+			wantBody:    types.Body{`{"a":"{{"{{"}}b}}"}`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var request harRequest
+			if err := json.Unmarshal([]byte(test.request), &request); err != nil {
+				t.Fatal(err)
+			}
+			requestURL, err := url.Parse(request.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			step, err := harRequestDefinition(request, requestURL)
+			if err != nil {
+				t.Fatalf("harRequestDefinition(%s) unexpected error: %v", test.request, err)
+			}
+			if got := step.URL.String(); got != test.wantURL {
+				t.Errorf("URL = %q, want %q", got, test.wantURL)
+			}
+			if !reflect.DeepEqual(step.Params, test.wantParams) {
+				t.Errorf("params = %v, want %v", step.Params, test.wantParams)
+			}
+			if !reflect.DeepEqual(step.Headers, test.wantHeaders) {
+				t.Errorf("headers = %v, want %v", step.Headers, test.wantHeaders)
+			}
+			if !reflect.DeepEqual(step.Cookies, test.wantCookies) {
+				t.Errorf("cookies = %v, want %v", step.Cookies, test.wantCookies)
+			}
+			if !reflect.DeepEqual(step.Body, test.wantBody) {
+				t.Errorf("body = %q, want %q", step.Body, test.wantBody)
+			}
+		})
+	}
+}
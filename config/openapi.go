@@ -0,0 +1,500 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/aykhans/dodo/types"
+	"github.com/aykhans/dodo/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// openAPIMethods are the operations of an OpenAPI path item, in the order they are imported.
+var openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// openAPIMaxDepth limits how many references are followed, in chains of references
+// and in the nested schemas of the request bodies.
+const openAPIMaxDepth = 8
+
+// openAPISpec is an OpenAPI 3 document decoded into generic maps and slices.
+type openAPISpec struct {
+	root    map[string]any
+	funcMap template.FuncMap
+}
+
+// ReadOpenAPI imports the operations of the configured OpenAPI 3 document as the requests
+// of the mix, one request per operation with the same weight. Only the configured operations
+// are imported, if any are set.
+// The query, header and cookie params and the JSON bodies are generated from their schemas with
+// the fakeit template functions, so every request sends different values. Since URLs aren't
+// templates, the path params are filled once, from the examples of their schemas if they have any.
+// It does nothing if no OpenAPI document is configured.
+func (config *Config) ReadOpenAPI() error {
+	if config.OpenAPI == nil || *config.OpenAPI == "" {
+		return nil
+	}
+	if len(config.Scenario) > 0 || len(config.Mix) > 0 {
+		return errors.New("OpenAPI spec cannot be used together with scenario, mix or HAR file")
+	}
+
+	data, err := os.ReadFile(*config.OpenAPI)
+	if err != nil {
+		return errors.New("failed to read OpenAPI spec from " + *config.OpenAPI)
+	}
+
+	spec := openAPISpec{
+		funcMap: *utils.NewFuncMapGenerator(config.newRand()).GetFuncMap(),
+	}
+	// JSON documents are valid YAML, so both are decoded the same way.
+	if err := yaml.Unmarshal(data, &spec.root); err != nil {
+		return fmt.Errorf("OpenAPI spec: %v", err)
+	}
+	if version, _ := spec.root["openapi"].(string); !strings.HasPrefix(version, "3.") {
+		return errors.New("OpenAPI spec: only OpenAPI 3 documents are supported")
+	}
+
+	baseURL, err := spec.baseURL(config.URL)
+	if err != nil {
+		return err
+	}
+
+	var (
+		definitions types.RequestDefinitions
+		matched     = make(map[string]bool)
+		paths       = openAPIMap(spec.root["paths"])
+	)
+	for _, path := range slices.Sorted(maps.Keys(paths)) {
+		item := spec.resolve(paths[path])
+		for _, method := range openAPIMethods {
+			operation := openAPIMap(item[method])
+			if operation == nil {
+				continue
+			}
+
+			name := openAPIOperationName(operation, method, path)
+			if len(config.OpenAPIOperations) > 0 {
+				selector := openAPIMatchOperation(config.OpenAPIOperations, operation, method, path)
+				if selector == "" {
+					continue
+				}
+				matched[selector] = true
+			}
+
+			definition, err := spec.requestDefinition(baseURL, path, method, item, operation)
+			if err != nil {
+				return fmt.Errorf("OpenAPI spec operation %s: %v", name, err)
+			}
+			definition.Name = name
+			definitions = append(definitions, definition)
+		}
+	}
+
+	for _, selector := range config.OpenAPIOperations {
+		if !matched[selector] {
+			return fmt.Errorf("OpenAPI spec has no operation %q", selector)
+		}
+	}
+	if len(definitions) == 0 {
+		return errors.New("OpenAPI spec has no operations")
+	}
+
+	config.Mix = definitions
+	return nil
+}
+
+// baseURL returns the URL the paths of the operations are appended to: the configured URL
+// if it is set, otherwise the first server URL of the document, with its variables set to their defaults.
+func (spec openAPISpec) baseURL(configURL *types.RequestURL) (url.URL, error) {
+	if !utils.IsNilOrZero(configURL) {
+		return configURL.URL, nil
+	}
+
+	if servers, _ := spec.root["servers"].([]any); len(servers) > 0 {
+		server := openAPIMap(servers[0])
+		serverURL, _ := server["url"].(string)
+		for name, variable := range openAPIMap(server["variables"]) {
+			if defaultValue, ok := openAPIMap(variable)["default"]; ok {
+				serverURL = strings.ReplaceAll(serverURL, "{"+name+"}", fmt.Sprint(defaultValue))
+			}
+		}
+
+		parsed, err := url.Parse(serverURL)
+		if err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") {
+			return *parsed, nil
+		}
+	}
+	return url.URL{}, errors.New("OpenAPI spec: url is required since the spec has no absolute http(s) server URL")
+}
+
+func (spec openAPISpec) requestDefinition(
+	baseURL url.URL,
+	path string,
+	method string,
+	item map[string]any,
+	operation map[string]any,
+) (types.RequestDefinition, error) {
+	definition := types.RequestDefinition{Method: strings.ToUpper(method)}
+	// The path is filled both decoded and escaped, so that slashes in the values stay escaped.
+	rawPath := path
+
+	for _, parameter := range spec.parameters(item, operation) {
+		name, _ := parameter["name"].(string)
+		in, _ := parameter["in"].(string)
+		schema := spec.resolve(parameter["schema"])
+
+		switch required, _ := parameter["required"].(bool); {
+		case in == "path":
+			value, err := spec.pathValue(parameter, schema)
+			if err != nil {
+				return definition, fmt.Errorf("path param %s: %v", name, err)
+			}
+			path = strings.ReplaceAll(path, "{"+name+"}", value)
+			rawPath = strings.ReplaceAll(rawPath, "{"+name+"}", url.PathEscape(value))
+		case !required:
+			// Optional params are left out, since random values of them are more likely to be rejected.
+		case in == "query":
+			definition.Params.AppendByKey(name, spec.valueTemplate(schema))
+		case in == "header":
+			definition.Headers.AppendByKey(name, spec.valueTemplate(schema))
+		case in == "cookie":
+			definition.Cookies.AppendByKey(name, spec.valueTemplate(schema))
+		}
+	}
+
+	requestURL := baseURL
+	requestURL.Path = strings.TrimSuffix(baseURL.Path, "/") + path
+	requestURL.RawPath = strings.TrimSuffix(baseURL.EscapedPath(), "/") + rawPath
+	requestURL.RawQuery = ""
+	requestURL.Fragment = ""
+	definition.URL = &types.RequestURL{URL: requestURL}
+
+	content := openAPIMap(spec.resolve(operation["requestBody"])["content"])
+	for _, mediaType := range slices.Sorted(maps.Keys(content)) {
+		if !openAPIIsJSON(mediaType) {
+			continue
+		}
+		if schema := openAPIMap(content[mediaType])["schema"]; schema != nil {
+			var body strings.Builder
+			spec.writeJSONTemplate(&body, schema, nil)
+			definition.Body = types.Body{body.String()}
+			definition.Headers.AppendByKey("Content-Type", mediaType)
+		}
+		break
+	}
+
+	return definition, nil
+}
+
+// parameters returns the parameters of the operation together with the ones of its path item,
+// which the operation can override.
+func (spec openAPISpec) parameters(item, operation map[string]any) []map[string]any {
+	var parameters []map[string]any
+	add := func(values any) {
+		list, _ := values.([]any)
+		for _, value := range list {
+			parameter := spec.resolve(value)
+			index := slices.IndexFunc(parameters, func(p map[string]any) bool {
+				return p["name"] == parameter["name"] && p["in"] == parameter["in"]
+			})
+			if index >= 0 {
+				parameters[index] = parameter
+			} else {
+				parameters = append(parameters, parameter)
+			}
+		}
+	}
+	add(item["parameters"])
+	add(operation["parameters"])
+	return parameters
+}
+
+// pathValue returns the value of the path param: its example, default or first enum value,
+// or a value generated once from its schema.
+func (spec openAPISpec) pathValue(parameter, schema map[string]any) (string, error) {
+	for _, source := range []map[string]any{parameter, schema} {
+		if example, ok := source["example"]; ok {
+			return fmt.Sprint(example), nil
+		}
+	}
+	if defaultValue, ok := schema["default"]; ok {
+		return fmt.Sprint(defaultValue), nil
+	}
+	if enum, _ := schema["enum"].([]any); len(enum) > 0 {
+		return fmt.Sprint(enum[0]), nil
+	}
+
+	t, err := template.New("default").Funcs(spec.funcMap).Parse(spec.valueTemplate(schema))
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, utils.NewTemplateData()); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// valueTemplate returns the template generating the values of a scalar schema,
+// based on its type, format, enum and bounds.
+func (spec openAPISpec) valueTemplate(schema map[string]any) string {
+	if enum, _ := schema["enum"].([]any); len(enum) > 0 {
+		return openAPIEnumTemplate(enum, func(value any) string { return fmt.Sprint(value) })
+	}
+
+	switch schemaType, _ := schema["type"].(string); schemaType {
+	case "integer":
+		low, high := openAPIRange(schema, "minimum", "maximum", 1, 1000)
+		return fmt.Sprintf("{{ fakeit_IntRange %d %d }}", int64(low), int64(high))
+	case "number":
+		low, high := openAPIRange(schema, "minimum", "maximum", 0, 1000)
+		return fmt.Sprintf("{{ fakeit_Float64Range %s %s }}", openAPIFloat(low), openAPIFloat(high))
+	case "boolean":
+		return "{{ fakeit_Bool }}"
+	}
+
+	switch format, _ := schema["format"].(string); format {
+	case "email":
+		return "{{ fakeit_Email }}"
+	case "uuid":
+		return "{{ fakeit_UUID }}"
+	case "date":
+		return `{{ (fakeit_Date).Format "2006-01-02" }}`
+	case "date-time":
+		return `{{ (fakeit_Date).Format "2006-01-02T15:04:05Z07:00" }}`
+	case "uri", "url":
+		return "{{ fakeit_URL }}"
+	case "hostname":
+		return "{{ fakeit_DomainName }}"
+	case "ipv4":
+		return "{{ fakeit_IPv4Address }}"
+	case "ipv6":
+		return "{{ fakeit_IPv6Address }}"
+	}
+
+	_, hasMinLength := schema["minLength"]
+	_, hasMaxLength := schema["maxLength"]
+	if hasMinLength || hasMaxLength {
+		low, high := openAPIRange(schema, "minLength", "maxLength", 1, 16)
+		return fmt.Sprintf("{{ fakeit_LetterNN %d %d }}", uint64(max(low, 0)), uint64(max(high, 0)))
+	}
+	return "{{ fakeit_Word }}"
+}
+
+// writeJSONTemplate writes the template of a JSON value of the schema node. Objects have all their
+// properties except the read-only ones, and arrays have as many items as their minimum, at least one.
+// Recursive references and schemas nested deeper than openAPIMaxDepth are written as null.
+func (spec openAPISpec) writeJSONTemplate(w *strings.Builder, node any, refs []string) {
+	if ref, ok := openAPIMap(node)["$ref"].(string); ok {
+		if slices.Contains(refs, ref) {
+			w.WriteString("null")
+			return
+		}
+		refs = append(refs, ref)
+	}
+	schema := spec.resolve(node)
+	if schema == nil || len(refs) > openAPIMaxDepth {
+		w.WriteString("null")
+		return
+	}
+
+	for _, key := range []string{"oneOf", "anyOf"} {
+		if alternatives, _ := schema[key].([]any); len(alternatives) > 0 {
+			spec.writeJSONTemplate(w, alternatives[0], refs)
+			return
+		}
+	}
+	if allOf, _ := schema["allOf"].([]any); len(allOf) > 0 {
+		schema = spec.mergeAllOf(schema, allOf)
+	}
+
+	schemaType, _ := schema["type"].(string)
+	if schemaType == "" && schema["properties"] != nil {
+		schemaType = "object"
+	}
+
+	switch {
+	case schemaType == "object":
+		properties := openAPIMap(schema["properties"])
+		w.WriteByte('{')
+		written := 0
+		for _, name := range slices.Sorted(maps.Keys(properties)) {
+			if readOnly, _ := spec.resolve(properties[name])["readOnly"].(bool); readOnly {
+				continue
+			}
+			if written > 0 {
+				w.WriteByte(',')
+			}
+			key, _ := json.Marshal(name)
+			w.Write(key)
+			w.WriteByte(':')
+			spec.writeJSONTemplate(w, properties[name], refs)
+			written++
+		}
+		w.WriteByte('}')
+
+	case schemaType == "array":
+		count, _ := openAPINumber(schema["minItems"])
+		w.WriteByte('[')
+		for i := range max(int(count), 1) {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			spec.writeJSONTemplate(w, schema["items"], refs)
+		}
+		w.WriteByte(']')
+
+	case schemaType == "integer", schemaType == "number", schemaType == "boolean":
+		if enum, _ := schema["enum"].([]any); len(enum) > 0 {
+			value, _ := json.Marshal(enum[0])
+			w.Write(value)
+			return
+		}
+		w.WriteString(spec.valueTemplate(schema))
+
+	case schemaType == "string", schema["enum"] != nil:
+		if enum, _ := schema["enum"].([]any); len(enum) > 0 {
+			// The enum values are picked as JSON literals, so that their quotes and backslashes are escaped.
+			w.WriteString(openAPIEnumTemplate(enum, func(value any) string {
+				literal, _ := json.Marshal(value)
+				return string(literal)
+			}))
+			return
+		}
+		w.WriteString(`"` + spec.valueTemplate(schema) + `"`)
+
+	default:
+		w.WriteString("null")
+	}
+}
+
+// mergeAllOf returns an object schema with the properties of the schema and of all its allOf schemas.
+func (spec openAPISpec) mergeAllOf(schema map[string]any, allOf []any) map[string]any {
+	properties := make(map[string]any)
+	for name, property := range openAPIMap(schema["properties"]) {
+		properties[name] = property
+	}
+	for _, item := range allOf {
+		for name, property := range openAPIMap(spec.resolve(item)["properties"]) {
+			properties[name] = property
+		}
+	}
+	return map[string]any{"type": "object", "properties": properties}
+}
+
+// resolve returns the object of the node, following its local $ref (e.g. "#/components/schemas/User")
+// if it has one. References to other documents and missing references resolve to nil.
+func (spec openAPISpec) resolve(node any) map[string]any {
+	object := openAPIMap(node)
+	// Chains of references are followed a limited number of times, so that cycles end.
+	for range openAPIMaxDepth {
+		ref, ok := object["$ref"].(string)
+		if !ok {
+			return object
+		}
+		pointer, ok := strings.CutPrefix(ref, "#/")
+		if !ok {
+			return nil
+		}
+
+		var current any = spec.root
+		for token := range strings.SplitSeq(pointer, "/") {
+			token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+			current = openAPIMap(current)[token]
+		}
+		object = openAPIMap(current)
+	}
+	return nil
+}
+
+// openAPIEnumTemplate returns the template picking one of the enum values, formatted by format.
+func openAPIEnumTemplate(enum []any, format func(any) string) string {
+	values := make([]string, len(enum))
+	for i, value := range enum {
+		values[i] = strconv.Quote(format(value))
+	}
+	return "{{ fakeit_RandomString " + strings.Join(values, " ") + " }}"
+}
+
+// openAPIOperationName returns the operationId of the operation, or its method and path if it has none.
+func openAPIOperationName(operation map[string]any, method, path string) string {
+	if operationID, _ := operation["operationId"].(string); operationID != "" {
+		return operationID
+	}
+	return strings.ToUpper(method) + " " + path
+}
+
+// openAPIMatchOperation returns the selector that matches the operation, by its operationId
+// or by its method and path (e.g. "GET /users/{id}"), or an empty string if none matches.
+func openAPIMatchOperation(selectors []string, operation map[string]any, method, path string) string {
+	operationID, _ := operation["operationId"].(string)
+	for _, selector := range selectors {
+		if operationID != "" && selector == operationID {
+			return selector
+		}
+		selectorMethod, selectorPath, ok := strings.Cut(selector, " ")
+		if ok && strings.EqualFold(selectorMethod, method) && strings.TrimSpace(selectorPath) == path {
+			return selector
+		}
+	}
+	return ""
+}
+
+// openAPIIsJSON reports whether the media type is JSON, such as application/json or application/problem+json.
+func openAPIIsJSON(mediaType string) bool {
+	mediaType, _, _ = strings.Cut(mediaType, ";")
+	return strings.HasSuffix(mediaType, "/json") || strings.HasSuffix(mediaType, "+json")
+}
+
+// openAPIRange returns the bounds of the schema, falling back to the defaults for the unset ones.
+// If only one bound is set and it doesn't fit the defaults, the other one is moved along with it.
+func openAPIRange(schema map[string]any, minKey, maxKey string, defaultLow, defaultHigh float64) (float64, float64) {
+	low, hasLow := openAPINumber(schema[minKey])
+	high, hasHigh := openAPINumber(schema[maxKey])
+	if !hasLow {
+		low = defaultLow
+	}
+	if !hasHigh {
+		high = defaultHigh
+	}
+	if high < low {
+		if hasLow {
+			high = low + (defaultHigh - defaultLow)
+		} else {
+			low = min(high, 0)
+		}
+	}
+	return low, high
+}
+
+func openAPINumber(value any) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// openAPIFloat formats the number as a float literal of the templates.
+func openAPIFloat(value float64) string {
+	formatted := strconv.FormatFloat(value, 'f', -1, 64)
+	if !strings.Contains(formatted, ".") {
+		formatted += ".0"
+	}
+	return formatted
+}
+
+func openAPIMap(node any) map[string]any {
+	object, _ := node.(map[string]any)
+	return object
+}
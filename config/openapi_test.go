@@ -0,0 +1,290 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/aykhans/dodo/types"
+	"github.com/aykhans/dodo/utils"
+)
+
+const testOpenAPISpec = `
+openapi: 3.0.3
+servers:
+  - url: "http://{host}/v1"
+    variables:
+      host: {default: "api.example.com:8080"}
+paths:
+  /users/{id}:
+    parameters:
+      - {name: id, in: path, required: true, schema: {type: string}, example: "42"}
+    get:
+      operationId: getUser
+      parameters:
+        - {name: fields, in: query, required: true, schema: {type: integer, minimum: 5, maximum: 10}}
+        - {name: verbose, in: query, schema: {type: boolean}}
+        - {name: X-Mode, in: header, required: true, schema: {type: string, enum: [fast, slow]}}
+        - {name: session, in: cookie, required: true, schema: {type: string, format: uuid}}
+    delete:
+      parameters:
+        - {name: id, in: path, required: true, schema: {type: integer, enum: [7, 8]}}
+  /users:
+    post:
+      operationId: createUser
+      requestBody:
+        content:
+          text/plain:
+            schema: {type: string}
+          application/json:
+            schema: {$ref: "#/components/schemas/User"}
+components:
+  schemas:
+    User:
+      type: object
+      properties:
+        id: {type: integer, readOnly: true}
+        name: {type: string, minLength: 3, maxLength: 5}
+        email: {type: string, format: email}
+        role: {type: string, enum: [admin, user]}
+        level: {type: integer, enum: [3, 4]}
+        tags: {type: array, minItems: 2, items: {type: string, format: uuid}}
+        parent: {$ref: "#/components/schemas/User"}
+`
+
+// readTestOpenAPI imports the spec with the given operations.
+func readTestOpenAPI(t *testing.T, spec string, operations ...string) (*Config, error) {
+	t.Helper()
+	specFile := filepath.Join(t.TempDir(), "spec.yaml")
+	if err := os.WriteFile(specFile, []byte(spec), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	config := NewConfig()
+	config.OpenAPI = &specFile
+	config.OpenAPIOperations = operations
+	return config, config.ReadOpenAPI()
+}
+
+func TestReadOpenAPIOperations(t *testing.T) {
+	tests := []struct {
+		name       string
+		operations []string
+		want       []string
+	}{
+		{
+			// The paths are sorted, and the path param of DELETE overrides the one of its path item.
+			name: "all operations",
+			want: []string{
+				"createUser POST http://api.example.com:8080/v1/users",
+				"getUser GET http://api.example.com:8080/v1/users/42",
+				"DELETE /users/{id} DELETE http://api.example.com:8080/v1/users/7",
+			},
+		},
+		{
+			name:       "selected by operationId and by method and path",
+			operations: []string{"getUser", "delete /users/{id}"},
+			want: []string{
+				"getUser GET http://api.example.com:8080/v1/users/42",
+				"DELETE /users/{id} DELETE http://api.example.com:8080/v1/users/7",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config, err := readTestOpenAPI(t, testOpenAPISpec, test.operations...)
+			if err != nil {
+				t.Fatalf("ReadOpenAPI() unexpected error: %v", err)
+			}
+			var got []string
+			for _, definition := range config.Mix {
+				got = append(got, definition.Name+" "+definition.Method+" "+definition.URL.String())
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ReadOpenAPI() imported\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(test.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestReadOpenAPIParams(t *testing.T) {
+	config, err := readTestOpenAPI(t, testOpenAPISpec, "getUser")
+	if err != nil {
+		t.Fatalf("ReadOpenAPI() unexpected error: %v", err)
+	}
+	definition := config.Mix[0]
+
+	// Optional params are left out.
+	wantParams := types.Params{{Key: "fields", Value: []string{"{{ fakeit_IntRange 5 10 }}"}}}
+	if !reflect.DeepEqual(definition.Params, wantParams) {
+		t.Errorf("params = %v, want %v", definition.Params, wantParams)
+	}
+	wantHeaders := types.Headers{{Key: "X-Mode", Value: []string{`{{ fakeit_RandomString "fast" "slow" }}`}}}
+	if !reflect.DeepEqual(definition.Headers, wantHeaders) {
+		t.Errorf("headers = %v, want %v", definition.Headers, wantHeaders)
+	}
+	wantCookies := types.Cookies{{Key: "session", Value: []string{"{{ fakeit_UUID }}"}}}
+	if !reflect.DeepEqual(definition.Cookies, wantCookies) {
+		t.Errorf("cookies = %v, want %v", definition.Cookies, wantCookies)
+	}
+}
+
+func TestReadOpenAPIBody(t *testing.T) {
+	config, err := readTestOpenAPI(t, testOpenAPISpec, "createUser")
+	if err != nil {
+		t.Fatalf("ReadOpenAPI() unexpected error: %v", err)
+	}
+	definition := config.Mix[0]
+
+	// The read-only id is left out, the recursive parent is null and tags have their minimum of items.
+	wantBody := `{` +
+		`"email":"{{ fakeit_Email }}",` +
+		`"level":3,` +
+		`"name":"{{ fakeit_LetterNN 3 5 }}",` +
+		`"parent":null,` +
+		`"role":{{ fakeit_RandomString "\"admin\"" "\"user\"" }},` +
+		`"tags":["{{ fakeit_UUID }}","{{ fakeit_UUID }}"]` +
+		`}`
+	if len(definition.Body) != 1 || definition.Body[0] != wantBody {
+		t.Fatalf("body = %q, want %q", definition.Body, wantBody)
+	}
+	wantHeaders := types.Headers{{Key: "Content-Type", Value: []string{"application/json"}}}
+	if !reflect.DeepEqual(definition.Headers, wantHeaders) {
+		t.Errorf("headers = %v, want %v", definition.Headers, wantHeaders)
+	}
+
+	assertOpenAPIBodyIsJSON(t, wantBody)
+}
+
+func TestReadOpenAPIEscapesValues(t *testing.T) {
+	spec := `
+openapi: 3.0.3
+servers: [{url: "http://api.example.com/v%201"}]
+paths:
+  /files/{path}:
+    post:
+      parameters:
+        - {name: path, in: path, required: true, schema: {type: string}, example: "a b/c"}
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              properties:
+                quote: {type: string, enum: ["a \"b\"", "c\\d"]}
+`
+	config, err := readTestOpenAPI(t, spec)
+	if err != nil {
+		t.Fatalf("ReadOpenAPI() unexpected error: %v", err)
+	}
+	definition := config.Mix[0]
+
+	// The path value is escaped once, keeping its slash apart from the ones of the path.
+	if got, want := definition.URL.String(), "http://api.example.com/v%201/files/a%20b%2Fc"; got != want {
+		t.Errorf("URL = %q, want %q", got, want)
+	}
+
+	// The enum values are picked as JSON strings, with their quotes and backslashes escaped.
+	wantBody := `{"quote":{{ fakeit_RandomString "\"a \\\"b\\\"\"" "\"c\\\\d\"" }}}`
+	if len(definition.Body) != 1 || definition.Body[0] != wantBody {
+		t.Fatalf("body = %q, want %q", definition.Body, wantBody)
+	}
+	assertOpenAPIBodyIsJSON(t, wantBody)
+}
+
+func TestReadOpenAPIErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		spec       string
+		operations []string
+		wantErr    string
+	}{
+		{
+			name:    "not OpenAPI 3",
+			spec:    `swagger: "2.0"`,
+			wantErr: "only OpenAPI 3 documents are supported",
+		},
+		{
+			name:    "relative server URL",
+			spec:    "openapi: 3.1.0\nservers: [{url: /v1}]\npaths: {/a: {get: {}}}",
+			wantErr: "url is required",
+		},
+		{
+			name:    "no operations",
+			spec:    "openapi: 3.1.0\nservers: [{url: http://a}]\npaths: {}",
+			wantErr: "has no operations",
+		},
+		{
+			name:       "unknown operation",
+			spec:       "openapi: 3.1.0\nservers: [{url: http://a}]\npaths: {/a: {get: {operationId: getA}}}",
+			operations: []string{"getA", "POST /a"},
+			wantErr:    `has no operation "POST /a"`,
+		},
+		{
+			name:    "invalid document",
+			spec:    "openapi: [",
+			wantErr: "OpenAPI spec:",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := readTestOpenAPI(t, test.spec, test.operations...)
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Fatalf("ReadOpenAPI() error = %v, want it to contain %q", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestOpenAPIValueTemplate(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema map[string]any
+		want   string
+	}{
+		{name: "enum", schema: map[string]any{"type": "string", "enum": []any{"a", 1, true}}, want: `{{ fakeit_RandomString "a" "1" "true" }}`},
+		{name: "integer", schema: map[string]any{"type": "integer"}, want: "{{ fakeit_IntRange 1 1000 }}"},
+		{name: "integer above default range", schema: map[string]any{"type": "integer", "minimum": 5000}, want: "{{ fakeit_IntRange 5000 5999 }}"},
+		{name: "integer below default range", schema: map[string]any{"type": "integer", "maximum": -5}, want: "{{ fakeit_IntRange -5 -5 }}"},
+		{name: "number", schema: map[string]any{"type": "number", "minimum": 0.5, "maximum": 2}, want: "{{ fakeit_Float64Range 0.5 2.0 }}"},
+		{name: "boolean", schema: map[string]any{"type": "boolean"}, want: "{{ fakeit_Bool }}"},
+		{name: "date", schema: map[string]any{"type": "string", "format": "date"}, want: `{{ (fakeit_Date).Format "2006-01-02" }}`},
+		{name: "uuid", schema: map[string]any{"type": "string", "format": "uuid"}, want: "{{ fakeit_UUID }}"},
+		{name: "length", schema: map[string]any{"type": "string", "maxLength": 4}, want: "{{ fakeit_LetterNN 1 4 }}"},
+		{name: "string", schema: map[string]any{"type": "string"}, want: "{{ fakeit_Word }}"},
+	}
+
+	var spec openAPISpec
+	for _, test := range tests {
+		if got := spec.valueTemplate(test.schema); got != test.want {
+			t.Errorf("%s: valueTemplate(%v) = %q, want %q", test.name, test.schema, got, test.want)
+		}
+	}
+}
+
+// assertOpenAPIBodyIsJSON checks that the body template generates valid JSON whatever values its functions return.
+func assertOpenAPIBodyIsJSON(t *testing.T, body string) {
+	t.Helper()
+	funcMap := *utils.NewFuncMapGenerator(rand.New(rand.NewSource(1))).GetFuncMap()
+	bodyTemplate, err := template.New("body").Funcs(funcMap).Parse(body)
+	if err != nil {
+		t.Fatalf("body template parse error: %v", err)
+	}
+	for range 20 {
+		var buf bytes.Buffer
+		if err := bodyTemplate.Execute(&buf, utils.NewTemplateData()); err != nil {
+			t.Fatalf("body template execute error: %v", err)
+		}
+		if !json.Valid(buf.Bytes()) {
+			t.Fatalf("body %s is not valid JSON", buf.String())
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/aykhans/dodo/types"
+)
+
+// maxRequestsFileLineSize is the maximum size of a line of a requests file, so a body can be up to about 64 MiB.
+const maxRequestsFileLineSize = 64 * 1024 * 1024
+
+// requestsFileLine is a request of a requests file.
+// Headers can be an object of header names to values, or a list like the headers of the config.
+type requestsFileLine struct {
+	Method  string          `json:"method"`
+	URL     string          `json:"url"`
+	Headers json.RawMessage `json:"headers"`
+	Body    string          `json:"body"`
+}
+
+// ReadRequestsFile reads the requests of the configured JSONL requests file, one request per line,
+// into the requests to replay. Lines without a method use the top-level method, and relative URLs
+// are resolved against the top-level URL. Empty lines are skipped.
+// It does nothing if no requests file is configured.
+func (config *Config) ReadRequestsFile() error {
+	if config.RequestsFile == nil || *config.RequestsFile == "" {
+		return nil
+	}
+
+	file, err := os.Open(*config.RequestsFile)
+	if err != nil {
+		return errors.New("failed to read requests file from " + *config.RequestsFile)
+	}
+	defer func() { _ = file.Close() }()
+
+	var (
+		scanner    = bufio.NewScanner(file)
+		lineNumber = 0
+		requests   types.RequestDefinitions
+	)
+	scanner.Buffer(nil, maxRequestsFileLineSize)
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		request, err := config.parseRequestsFileLine([]byte(line))
+		if err != nil {
+			return fmt.Errorf("requests file line %d: %v", lineNumber, err)
+		}
+		requests = append(requests, request)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read requests file from %s: %v", *config.RequestsFile, err)
+	}
+	if len(requests) == 0 {
+		return errors.New("requests file has no requests")
+	}
+
+	config.Replay = requests
+	return nil
+}
+
+func (config *Config) parseRequestsFileLine(data []byte) (types.RequestDefinition, error) {
+	var line requestsFileLine
+	if err := json.Unmarshal(data, &line); err != nil {
+		return types.RequestDefinition{}, fmt.Errorf("invalid JSON: %v", err)
+	}
+
+	if line.URL == "" {
+		return types.RequestDefinition{}, errors.New("url is required")
+	}
+	var requestURL types.RequestURL
+	if err := requestURL.Set(line.URL); err != nil {
+		return types.RequestDefinition{}, fmt.Errorf("invalid url: %v", err)
+	}
+	if !requestURL.IsAbs() {
+		if config.URL == nil || !config.URL.IsAbs() {
+			return types.RequestDefinition{}, errors.New("url must be absolute when there is no top-level url to resolve it against")
+		}
+		requestURL.URL = *config.URL.ResolveReference(&requestURL.URL)
+	}
+	if requestURL.Scheme != "http" && requestURL.Scheme != "https" {
+		return types.RequestDefinition{}, errors.New("url scheme must be http or https")
+	}
+
+	headers, err := parseRequestsFileHeaders(line.Headers)
+	if err != nil {
+		return types.RequestDefinition{}, err
+	}
+
+	request := types.RequestDefinition{
+		Method:  strings.ToUpper(line.Method),
+		URL:     &requestURL,
+		Params:  urlParams(requestURL.URL),
+		Headers: headers,
+	}
+	request.URL.RawQuery = ""
+	if request.Method == "" && config.Method != nil {
+		request.Method = *config.Method
+	}
+	if line.Body != "" {
+		request.Body = types.Body{line.Body}
+	}
+	return request, nil
+}
+
+// parseRequestsFileHeaders parses the headers of a requests file line,
+// given as an object of header names to values or as a list like the headers of the config.
+// The headers of an object are sorted by name.
+func parseRequestsFileHeaders(data json.RawMessage) (types.Headers, error) {
+	if len(data) == 0 || string(data) == "null" {
+		return nil, nil
+	}
+
+	var object map[string]string
+	if err := json.Unmarshal(data, &object); err == nil {
+		headers := make(types.Headers, 0, len(object))
+		for _, key := range slices.Sorted(maps.Keys(object)) {
+			headers = append(headers, types.KeyValue[string, []string]{Key: key, Value: []string{object[key]}})
+		}
+		return headers, nil
+	}
+
+	var headers types.Headers
+	if err := json.Unmarshal(data, &headers); err != nil {
+		return nil, errors.New("invalid headers: should be an object or a list of objects")
+	}
+	return headers, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
 	"syscall"
@@ -15,6 +16,10 @@ import (
 	"github.com/jedib0t/go-pretty/v6/text"
 )
 
+// exitCodeThresholdsFailed is the exit code of a run that completed but failed one or more thresholds.
+// It is distinct from the exit code of errors, so that pipelines can tell them apart.
+const exitCodeThresholdsFailed = 99
+
 func main() {
 	conf := config.NewConfig()
 	configFile, err := conf.ReadCLI()
@@ -22,6 +27,16 @@ func main() {
 		utils.PrintErrAndExit(err)
 	}
 
+	// The curl command overrides the config file, and the other CLI flags override the curl command.
+	if conf.Curl != nil {
+		curlConf, err := config.ParseCurl(*conf.Curl)
+		if err != nil {
+			utils.PrintErrAndExit(err)
+		}
+		curlConf.MergeConfig(conf)
+		conf = curlConf
+	}
+
 	if configFile.String() != "" {
 		tempConf := config.NewConfig()
 		if err := tempConf.ReadFile(configFile); err != nil {
@@ -30,8 +45,28 @@ func main() {
 		tempConf.MergeConfig(conf)
 		conf = tempConf
 	}
+
+	if err := conf.ReadHAR(); err != nil {
+		utils.PrintErrAndExit(err)
+	}
+	if err := conf.ReadOpenAPI(); err != nil {
+		utils.PrintErrAndExit(err)
+	}
+	if conf.ExportConfig != nil {
+		exportConfig(conf, *conf.ExportConfig)
+		return
+	}
 	conf.SetDefaults()
 
+	if err := conf.ReadRequestsFile(); err != nil {
+		utils.PrintErrAndExit(err)
+	}
+	if err := conf.ReadAccessLog(); err != nil {
+		utils.PrintErrAndExit(err)
+	}
+	if err := conf.ReadData(); err != nil {
+		utils.PrintErrAndExit(err)
+	}
 	if errs := conf.Validate(); len(errs) > 0 {
 		utils.PrintErrAndExit(errors.Join(errs...))
 	}
@@ -40,25 +75,87 @@ func main() {
 	requestConf.Print()
 
 	if !requestConf.Yes {
-		response := config.CLIYesOrNoReader("Do you want to continue?", false)
+		response := config.CLIYesOrNoReader(requestConf.LogWriter(), "Do you want to continue?", false)
 		if !response {
-			utils.PrintAndExit("Exiting...\n")
+			utils.PrintAndExit(requestConf.LogWriter(), "Exiting...\n")
 		}
 	}
 
+	// The output file is created before the run, so that an invalid path doesn't waste it,
+	// and removed if the run fails, so that it only ever holds a complete report.
+	var (
+		output     io.Writer = os.Stdout
+		outputFile *os.File
+	)
+	if requestConf.OutputFile != "" {
+		outputFile, err = os.Create(requestConf.OutputFile)
+		if err != nil {
+			utils.PrintErrAndExit(err)
+		}
+		output = outputFile
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	go listenForTermination(func() { cancel() })
 
-	responses, err := requests.Run(ctx, requestConf)
+	result, err := requests.Run(ctx, requestConf)
 	if err != nil {
+		removeOutputFile(outputFile)
 		if err == types.ErrInterrupt {
-			fmt.Println(text.FgYellow.Sprint(err.Error()))
+			fmt.Fprintln(requestConf.LogWriter(), text.FgYellow.Sprint(err.Error()))
 			return
 		}
 		utils.PrintErrAndExit(err)
 	}
 
-	responses.Print()
+	switch requestConf.Output {
+	case config.OutputJSON:
+		if err := requests.NewReport(result, conf).WriteJSON(output); err != nil {
+			removeOutputFile(outputFile)
+			utils.PrintErrAndExit(err)
+		}
+	default:
+		result.Print(output)
+	}
+
+	if outputFile != nil {
+		if err := outputFile.Close(); err != nil {
+			utils.PrintErrAndExit(err)
+		}
+	}
+	if !result.ThresholdsPassed() {
+		os.Exit(exitCodeThresholdsFailed)
+	}
+}
+
+// removeOutputFile closes and removes the output file of an unfinished report, if there is one.
+func removeOutputFile(file *os.File) {
+	if file == nil {
+		return
+	}
+	_ = file.Close()
+	_ = os.Remove(file.Name())
+}
+
+// exportConfig writes the config to the file at path, or to stdout if path is "-".
+func exportConfig(conf *config.Config, path string) {
+	if path == "-" {
+		if err := conf.WriteYAML(os.Stdout); err != nil {
+			utils.PrintErrAndExit(err)
+		}
+		return
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		utils.PrintErrAndExit(err)
+	}
+	if err := conf.WriteYAML(file); err != nil {
+		utils.PrintErrAndExit(err)
+	}
+	if err := file.Close(); err != nil {
+		utils.PrintErrAndExit(err)
+	}
 }
 
 func listenForTermination(do func()) {
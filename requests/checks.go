@@ -0,0 +1,166 @@
+package requests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+
+	"github.com/aykhans/dodo/types"
+	"github.com/aykhans/dodo/utils"
+	"github.com/valyala/fasthttp"
+)
+
+// CheckResult is the outcome of a check on a single response.
+type CheckResult struct {
+	Name   string
+	Passed bool
+}
+
+// CheckStats counts the responses that passed and failed a check.
+type CheckStats struct {
+	Passed uint64
+	Failed uint64
+}
+
+// checkedResponse is a response being checked. The body is decompressed and
+// parsed as JSON at most once, and only if a check needs it.
+type checkedResponse struct {
+	response   *fasthttp.Response
+	body       []byte
+	bodyErr    error
+	bodyRead   bool
+	json       any
+	jsonErr    error
+	jsonParsed bool
+}
+
+// Body returns the body of the response, decompressed according to its Content-Encoding.
+func (r *checkedResponse) Body() ([]byte, error) {
+	if !r.bodyRead {
+		r.bodyRead = true
+		r.body, r.bodyErr = r.response.BodyUncompressed()
+	}
+	return r.body, r.bodyErr
+}
+
+// JSON returns the body of the response decoded as JSON.
+func (r *checkedResponse) JSON() (any, error) {
+	if !r.jsonParsed {
+		r.jsonParsed = true
+		body, err := r.Body()
+		if err != nil {
+			r.jsonErr = err
+		} else {
+			r.jsonErr = json.Unmarshal(body, &r.json)
+		}
+	}
+	return r.json, r.jsonErr
+}
+
+// responseCheck is a check prepared for evaluating responses.
+type responseCheck struct {
+	name  string
+	check func(response *checkedResponse) bool
+}
+
+// newResponseChecks prepares the checks for evaluating responses.
+// A check whose regex or expected value can't be used fails on every response.
+func newResponseChecks(checks types.Checks) []responseCheck {
+	responseChecks := make([]responseCheck, len(checks))
+	for i, check := range checks {
+		responseChecks[i] = responseCheck{
+			name:  check.String(),
+			check: getCheckFunc(check),
+		}
+	}
+	return responseChecks
+}
+
+func getCheckFunc(check types.Check) func(response *checkedResponse) bool {
+	switch {
+	case len(check.Status) > 0:
+		return func(response *checkedResponse) bool {
+			statusCode := response.response.StatusCode()
+			for _, status := range check.Status {
+				if statusCode == status {
+					return true
+				}
+			}
+			return false
+		}
+
+	case check.Header != "":
+		if check.Equals == nil {
+			return func(response *checkedResponse) bool {
+				return response.response.Header.Peek(check.Header) != nil
+			}
+		}
+		expected := []byte(fmt.Sprint(check.Equals))
+		return func(response *checkedResponse) bool {
+			value := response.response.Header.Peek(check.Header)
+			return value != nil && bytes.Equal(value, expected)
+		}
+
+	case check.BodyContains != "":
+		substring := []byte(check.BodyContains)
+		return func(response *checkedResponse) bool {
+			body, err := response.Body()
+			return err == nil && bytes.Contains(body, substring)
+		}
+
+	case check.BodyRegex != "":
+		re, err := regexp.Compile(check.BodyRegex)
+		if err != nil {
+			return func(*checkedResponse) bool { return false }
+		}
+		return func(response *checkedResponse) bool {
+			body, err := response.Body()
+			return err == nil && re.Match(body)
+		}
+
+	case check.JSONPath != "":
+		var expected any
+		if check.Equals != nil {
+			// The expected value is normalized to the types encoding/json decodes into,
+			// so that e.g. an integer from a YAML config equals the float64 of the response.
+			data, err := json.Marshal(check.Equals)
+			if err != nil || json.Unmarshal(data, &expected) != nil {
+				return func(*checkedResponse) bool { return false }
+			}
+		}
+		return func(response *checkedResponse) bool {
+			data, err := response.JSON()
+			if err != nil {
+				return false
+			}
+			value, ok := utils.LookupJSONPath(data, check.JSONPath)
+			if !ok {
+				return false
+			}
+			return check.Equals == nil || reflect.DeepEqual(value, expected)
+		}
+
+	case check.MaxBodySize != nil:
+		return func(response *checkedResponse) bool {
+			return uint64(len(response.response.Body())) <= *check.MaxBodySize
+		}
+	}
+
+	return func(*checkedResponse) bool { return false }
+}
+
+// evaluateChecks evaluates all checks on the response and returns their outcomes.
+// It returns nil if there are no checks.
+func evaluateChecks(checks []responseCheck, response *checkedResponse) []CheckResult {
+	if len(checks) == 0 {
+		return nil
+	}
+
+	results := make([]CheckResult, len(checks))
+	for i, check := range checks {
+		results[i] = CheckResult{Name: check.name, Passed: check.check(response)}
+	}
+	return results
+}
@@ -0,0 +1,66 @@
+package requests
+
+import (
+	"testing"
+
+	"github.com/aykhans/dodo/types"
+	"github.com/valyala/fasthttp"
+	"gopkg.in/yaml.v3"
+)
+
+func TestGetCheckFunc(t *testing.T) {
+	response := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(response)
+	response.SetStatusCode(201)
+	response.Header.Set("X-Count", "3")
+	response.SetBodyString(`{"data": {"id": 7, "price": 9.5, "name": "dodo", "ok": true, "tags": ["a", "b"], "none": null}}`)
+
+	tests := []struct {
+		// check is decoded from YAML like the checks of the config file, so its equals value is an int, a string, etc.
+		check string
+		want  bool
+	}{
+		{check: "status: [200, 201]", want: true},
+		{check: "status: [200]", want: false},
+		{check: "header: X-Count", want: true},
+		{check: "header: X-Missing", want: false},
+		{check: "{header: X-Count, equals: 3}", want: true},
+		{check: "{header: X-Count, equals: \"4\"}", want: false},
+		{check: "body_contains: '\"name\": \"dodo\"'", want: true},
+		{check: "body_regex: '\"id\": \\d+'", want: true},
+		{check: "body_regex: '('", want: false},
+		{check: "json_path: data.none", want: true},
+		{check: "json_path: data.missing", want: false},
+		{check: "{json_path: data.id, equals: 7}", want: true},
+		{check: "{json_path: $.data.id, equals: 7.0}", want: true},
+		{check: "{json_path: data.id, equals: \"7\"}", want: false},
+		{check: "{json_path: data.price, equals: 9.5}", want: true},
+		{check: "{json_path: data.ok, equals: true}", want: true},
+		{check: "{json_path: data.tags, equals: [a, b]}", want: true},
+		{check: "{json_path: data.tags.1, equals: a}", want: false},
+		{check: "{json_path: data, equals: {id: 7, price: 9.5, name: dodo, ok: true, tags: [a, b], none: null}}", want: true},
+		{check: "max_body_size: 1000", want: true},
+		{check: "max_body_size: 10", want: false},
+	}
+
+	for _, test := range tests {
+		var check types.Check
+		if err := yaml.Unmarshal([]byte(test.check), &check); err != nil {
+			t.Fatalf("invalid check %q: %v", test.check, err)
+		}
+		if got := getCheckFunc(check)(&checkedResponse{response: response}); got != test.want {
+			t.Errorf("check %q = %v, want %v", test.check, got, test.want)
+		}
+	}
+}
+
+func TestGetCheckFuncInvalidJSON(t *testing.T) {
+	response := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(response)
+	response.SetBodyString("not json")
+
+	check := getCheckFunc(types.Check{JSONPath: "$"})
+	if check(&checkedResponse{response: response}) {
+		t.Errorf("json_path check passed on a body that isn't JSON")
+	}
+}
@@ -5,9 +5,11 @@ import (
 	"crypto/tls"
 	"errors"
 	"math/rand"
+	"net"
 	"net/url"
 	"time"
 
+	"github.com/aykhans/dodo/types"
 	"github.com/aykhans/dodo/utils"
 	"github.com/valyala/fasthttp"
 	"github.com/valyala/fasthttp/fasthttpproxy"
@@ -15,6 +17,45 @@ import (
 
 type ClientGeneratorFunc func() *fasthttp.HostClient
 
+// clientPool holds the clients of every host the requests are sent to,
+// keyed by the scheme and host of the request URL.
+type clientPool map[string][]*fasthttp.HostClient
+
+func clientPoolKey(URL url.URL) string {
+	return URL.Scheme + "://" + URL.Host
+}
+
+// get returns the clients for the host of the URL.
+func (pool clientPool) get(URL url.URL) []*fasthttp.HostClient {
+	return pool[clientPoolKey(URL)]
+}
+
+// getClientPool initializes the clients of every distinct host of the request definitions,
+// in the same way as getClients.
+func getClientPool(
+	ctx context.Context,
+	timeout time.Duration,
+	proxies []url.URL,
+	maxConns uint,
+	definitions types.RequestDefinitions,
+	skipVerify bool,
+) clientPool {
+	pool := make(clientPool)
+	for _, definition := range definitions {
+		key := clientPoolKey(definition.URL.URL)
+		if _, ok := pool[key]; ok {
+			continue
+		}
+
+		clients := getClients(ctx, timeout, proxies, maxConns, definition.URL.URL, skipVerify)
+		if clients == nil {
+			return nil
+		}
+		pool[key] = clients
+	}
+	return pool
+}
+
 // getClients initializes and returns a slice of fasthttp.HostClient based on the provided parameters.
 // It can either return clients with proxies or a single client without proxies.
 func getClients(
@@ -26,6 +67,9 @@ func getClients(
 	skipVerify bool,
 ) []*fasthttp.HostClient {
 	isTLS := URL.Scheme == "https"
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: skipVerify,
+	}
 
 	if proxiesLen := len(proxies); proxiesLen > 0 {
 		clients := make([]*fasthttp.HostClient, 0, proxiesLen)
@@ -41,13 +85,12 @@ func getClients(
 			}
 
 			clients = append(clients, &fasthttp.HostClient{
-				MaxConns: int(maxConns),
-				IsTLS:    isTLS,
-				TLSConfig: &tls.Config{
-					InsecureSkipVerify: skipVerify,
-				},
+				MaxConns:            int(maxConns),
+				IsTLS:               isTLS,
+				TLSConfig:           tlsConfig,
 				Addr:                addr,
-				Dial:                dialFunc,
+				Dial:                getTracingDialFunc(dialFunc, isTLS, tlsConfig, timeout),
+				Transport:           newTracingTransport(proxy.String()),
 				MaxIdleConnDuration: timeout,
 				MaxConnDuration:     timeout,
 				WriteTimeout:        timeout,
@@ -59,12 +102,12 @@ func getClients(
 	}
 
 	client := &fasthttp.HostClient{
-		MaxConns: int(maxConns),
-		IsTLS:    isTLS,
-		TLSConfig: &tls.Config{
-			InsecureSkipVerify: skipVerify,
-		},
+		MaxConns:            int(maxConns),
+		IsTLS:               isTLS,
+		TLSConfig:           tlsConfig,
 		Addr:                URL.Host,
+		Dial:                getTracingDialFunc(nil, isTLS, tlsConfig, timeout),
+		Transport:           newTracingTransport(""),
 		MaxIdleConnDuration: timeout,
 		MaxConnDuration:     timeout,
 		WriteTimeout:        timeout,
@@ -96,6 +139,110 @@ func getDialFunc(proxy *url.URL, timeout time.Duration) (fasthttp.DialFunc, erro
 	return dialer, nil
 }
 
+// getTracingDialFunc returns a fasthttp.DialFunc that opens connections the same way as fasthttp
+// and records the time spent in each dial phase on the returned connection.
+// Without a proxy dial func, the address is resolved and connected to separately, so the DNS
+// lookup and TCP connect are timed apart; through a proxy the whole dial is timed as connect.
+// For TLS clients, the handshake is performed and timed here instead of by fasthttp.
+func getTracingDialFunc(
+	proxyDial fasthttp.DialFunc,
+	isTLS bool,
+	tlsConfig *tls.Config,
+	timeout time.Duration,
+) fasthttp.DialFunc {
+	return func(addr string) (net.Conn, error) {
+		// fasthttp doesn't add the default port for custom dial funcs.
+		addr = fasthttp.AddMissingPort(addr, isTLS)
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+
+		var (
+			conn       net.Conn
+			dialPhases Phases
+		)
+		if proxyDial != nil {
+			startTime := time.Now()
+			conn, err = proxyDial(addr)
+			dialPhases.Connect = time.Since(startTime)
+		} else {
+			conn, dialPhases.DNS, dialPhases.Connect, err = dialDirect(host, port, timeout)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if isTLS {
+			config := tlsConfig.Clone()
+			if config.ServerName == "" {
+				config.ServerName = host
+			}
+			tlsConn := tls.Client(conn, config)
+
+			startTime := time.Now()
+			if err := tlsConn.SetDeadline(startTime.Add(timeout)); err != nil {
+				conn.Close()
+				return nil, err
+			}
+			if err := tlsConn.Handshake(); err != nil {
+				conn.Close()
+				if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+					return nil, fasthttp.ErrTLSHandshakeTimeout
+				}
+				return nil, err
+			}
+			dialPhases.TLS = time.Since(startTime)
+
+			if err := tlsConn.SetDeadline(time.Time{}); err != nil {
+				conn.Close()
+				return nil, err
+			}
+			conn = tlsConn
+		}
+
+		return &tracedConn{Conn: conn, dialPhases: dialPhases}, nil
+	}
+}
+
+// dialDirect resolves the IPv4 addresses of the host, like fasthttp's default dialer, and connects
+// to the first of them that accepts the connection, returning the time spent on the DNS lookup and on connecting.
+// The DNS duration is zero if the host is already an IP address.
+func dialDirect(host, port string, timeout time.Duration) (net.Conn, time.Duration, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var (
+		ips []net.IP
+		dns time.Duration
+	)
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		startTime := time.Now()
+		var err error
+		ips, err = net.DefaultResolver.LookupIP(ctx, "ip4", host)
+		dns = time.Since(startTime)
+		if err != nil {
+			return nil, dns, 0, err
+		}
+	}
+
+	var (
+		dialer    net.Dialer
+		startTime = time.Now()
+		lastErr   error
+	)
+	for _, ip := range ips {
+		conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, dns, time.Since(startTime), nil
+		}
+		lastErr = err
+	}
+	return nil, dns, time.Since(startTime), lastErr
+}
+
 // getSharedClientFuncMultiple returns a ClientGeneratorFunc that cycles through a list of fasthttp.HostClient instances.
 // The function uses a local random number generator to determine the starting index and stop index for cycling through the clients.
 // The returned function isn't thread-safe and should be used in a single-threaded context.
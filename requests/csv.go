@@ -0,0 +1,96 @@
+package requests
+
+import (
+	"encoding/csv"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var csvHeader = []string{
+	"timestamp",
+	"dodo",
+	"proxy",
+	"step",
+	"response",
+	"latency_ms",
+	"corrected_latency_ms",
+	"bytes_sent",
+	"bytes_received",
+	"failed_checks",
+}
+
+// csvWriter streams one row per completed request to a CSV file as the run progresses.
+// Only a small write buffer is kept in memory. It is safe for concurrent use.
+type csvWriter struct {
+	mu     sync.Mutex
+	file   *os.File
+	writer *csv.Writer
+}
+
+// newCSVWriter creates the file at the given path and writes the CSV header to it.
+// It returns nil without an error if the path is empty, which disables the CSV export.
+func newCSVWriter(path string) (*csvWriter, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &csvWriter{file: file, writer: csv.NewWriter(file)}
+	if err := w.writer.Write(csvHeader); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// record writes the row of a request that completed now.
+// The timestamp of the row is the time the request was sent, the step is empty
+// for requests outside of scenarios, and
+// the names of the failed checks of the response are joined with "; ".
+func (w *csvWriter) record(
+	dodoID int64,
+	step string,
+	category string,
+	latency, correctedLatency time.Duration,
+	trace Trace,
+	failedChecks []string,
+) {
+	row := []string{
+		time.Now().Add(-latency).Format(time.RFC3339Nano),
+		strconv.FormatInt(dodoID, 10),
+		trace.Proxy,
+		step,
+		category,
+		strconv.FormatFloat(milliseconds(latency), 'f', -1, 64),
+		strconv.FormatFloat(milliseconds(correctedLatency), 'f', -1, 64),
+		strconv.FormatUint(trace.BytesSent, 10),
+		strconv.FormatUint(trace.BytesReceived, 10),
+		strings.Join(failedChecks, "; "),
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	// Write errors are kept by the csv.Writer and returned by Close.
+	_ = w.writer.Write(row)
+}
+
+// Close flushes the buffered rows and closes the file.
+// It returns the first error that occurred while writing or closing.
+func (w *csvWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.writer.Flush()
+	if err := w.writer.Error(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
@@ -0,0 +1,42 @@
+package requests
+
+import (
+	"math/rand"
+	"sync/atomic"
+
+	"github.com/aykhans/dodo/config"
+)
+
+// dataCursor picks the rows of the data file for the iterations of the dodos in the configured mode.
+// The position of the sequential and once modes is shared by all dodos, so consecutive iterations
+// of the run use consecutive rows; the random mode picks with the random generator of each dodo,
+// and in the unique mode each dodo uses the row of its own index in every iteration.
+type dataCursor struct {
+	mode     string
+	rows     []map[string]any
+	position atomic.Uint64
+}
+
+func newDataCursor(mode string, rows []map[string]any) *dataCursor {
+	return &dataCursor{mode: mode, rows: rows}
+}
+
+// next returns the row of the next iteration of the dodo, or false once all rows
+// were used in once mode.
+func (cursor *dataCursor) next(uid int64, localRand *rand.Rand) (map[string]any, bool) {
+	count := uint64(len(cursor.rows))
+	switch cursor.mode {
+	case config.DataModeRandom:
+		return cursor.rows[localRand.Intn(len(cursor.rows))], true
+	case config.DataModeUnique:
+		return cursor.rows[uint64(uid)%count], true
+	case config.DataModeOnce:
+		position := cursor.position.Add(1) - 1
+		if position >= count {
+			return nil, false
+		}
+		return cursor.rows[position], true
+	default:
+		return cursor.rows[(cursor.position.Add(1)-1)%count], true
+	}
+}
@@ -0,0 +1,103 @@
+package requests
+
+import (
+	"math/rand"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/aykhans/dodo/config"
+)
+
+func testDataRows(count int) []map[string]any {
+	rows := make([]map[string]any, count)
+	for i := range rows {
+		rows[i] = map[string]any{"id": i}
+	}
+	return rows
+}
+
+func TestDataCursorOnceExhaustion(t *testing.T) {
+	const count = 100
+	cursor := newDataCursor(config.DataModeOnce, testDataRows(count))
+
+	// The dodos share the position, so every row is used exactly once over all of them.
+	var (
+		mu   sync.Mutex
+		used = make(map[int]int)
+		wg   sync.WaitGroup
+	)
+	for uid := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				row, ok := cursor.next(int64(uid), nil)
+				if !ok {
+					return
+				}
+				mu.Lock()
+				used[row["id"].(int)]++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(used) != count {
+		t.Errorf("used %d rows, want %d", len(used), count)
+	}
+	for id, times := range used {
+		if times != 1 {
+			t.Errorf("row %d used %d times, want once", id, times)
+		}
+	}
+	if row, ok := cursor.next(0, nil); ok {
+		t.Errorf("next() after the last row = %v, true, want false", row)
+	}
+}
+
+func TestDataCursorUnique(t *testing.T) {
+	cursor := newDataCursor(config.DataModeUnique, testDataRows(3))
+
+	// Each dodo keeps the row of its own index, wrapping around when there are more dodos than rows.
+	for iteration := range 3 {
+		for uid, wantID := range []int{0, 1, 2, 0, 1} {
+			row, ok := cursor.next(int64(uid), nil)
+			if !ok || row["id"] != wantID {
+				t.Errorf("iteration %d: next(%d) = %v, %v, want row %d", iteration, uid, row, ok, wantID)
+			}
+		}
+	}
+}
+
+func TestDataCursorSequential(t *testing.T) {
+	cursor := newDataCursor(config.DataModeSequential, testDataRows(3))
+	var got []int
+	for uid := range 7 {
+		row, ok := cursor.next(int64(uid%2), nil)
+		if !ok {
+			t.Fatalf("next() = false, want the sequential mode to wrap around")
+		}
+		got = append(got, row["id"].(int))
+	}
+	if want := []int{0, 1, 2, 0, 1, 2, 0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("rows = %v, want %v", got, want)
+	}
+}
+
+func TestDataCursorRandom(t *testing.T) {
+	cursor := newDataCursor(config.DataModeRandom, testDataRows(3))
+	localRand := rand.New(rand.NewSource(1))
+	seen := make(map[int]bool)
+	for range 100 {
+		row, ok := cursor.next(0, localRand)
+		if !ok {
+			t.Fatalf("next() = false, want the random mode to never run out of rows")
+		}
+		seen[row["id"].(int)] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("random mode picked %v, want all 3 rows", seen)
+	}
+}
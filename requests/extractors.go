@@ -0,0 +1,131 @@
+package requests
+
+import (
+	"encoding/json"
+	"regexp"
+
+	"github.com/aykhans/dodo/types"
+	"github.com/aykhans/dodo/utils"
+	"github.com/valyala/fasthttp"
+)
+
+// ExtractResult is the outcome of an extractor on a single response.
+type ExtractResult struct {
+	Var       string
+	Extracted bool
+}
+
+// ExtractStats counts the responses a variable was extracted from and the ones it was missing from.
+type ExtractStats struct {
+	Extracted uint64
+	Missed    uint64
+}
+
+// responseExtractor is an extractor prepared for capturing values from responses.
+type responseExtractor struct {
+	variable     string
+	defaultValue *string
+	extract      func(response *checkedResponse) (string, bool)
+}
+
+// newResponseExtractors prepares the extractors for capturing values from responses.
+// An extractor whose regex can't be used fails on every response.
+func newResponseExtractors(extractors types.Extractors) []responseExtractor {
+	responseExtractors := make([]responseExtractor, len(extractors))
+	for i, extractor := range extractors {
+		responseExtractors[i] = responseExtractor{
+			variable:     extractor.Var,
+			defaultValue: extractor.Default,
+			extract:      getExtractFunc(extractor),
+		}
+	}
+	return responseExtractors
+}
+
+func getExtractFunc(extractor types.Extractor) func(response *checkedResponse) (string, bool) {
+	switch {
+	case extractor.JSONPath != "":
+		return func(response *checkedResponse) (string, bool) {
+			data, err := response.JSON()
+			if err != nil {
+				return "", false
+			}
+			value, ok := utils.LookupJSONPath(data, extractor.JSONPath)
+			if !ok || value == nil {
+				return "", false
+			}
+			if value, ok := value.(string); ok {
+				return value, true
+			}
+			// Numbers, booleans, objects and arrays are extracted as JSON.
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return "", false
+			}
+			return string(encoded), true
+		}
+
+	case extractor.Regex != "":
+		re, err := regexp.Compile(extractor.Regex)
+		if err != nil {
+			return func(*checkedResponse) (string, bool) { return "", false }
+		}
+		return func(response *checkedResponse) (string, bool) {
+			body, err := response.Body()
+			if err != nil {
+				return "", false
+			}
+			match := re.FindSubmatch(body)
+			if match == nil {
+				return "", false
+			}
+			if len(match) > 1 {
+				return string(match[1]), true
+			}
+			return string(match[0]), true
+		}
+
+	case extractor.Header != "":
+		return func(response *checkedResponse) (string, bool) {
+			value := response.response.Header.Peek(extractor.Header)
+			return string(value), value != nil
+		}
+
+	case extractor.Cookie != "":
+		return func(response *checkedResponse) (string, bool) {
+			cookie := fasthttp.AcquireCookie()
+			defer fasthttp.ReleaseCookie(cookie)
+			cookie.SetKey(extractor.Cookie)
+			if !response.response.Header.Cookie(cookie) {
+				return "", false
+			}
+			return string(cookie.Value()), true
+		}
+	}
+
+	return func(*checkedResponse) (string, bool) { return "", false }
+}
+
+// evaluateExtractors captures the values of the extractors from the response into the variables
+// of the template data and returns the outcome of each extraction, which misses if the value couldn't be extracted.
+// If an extraction fails, the variable is set to the default value of the extractor, if any.
+// It returns nil if there are no extractors.
+func evaluateExtractors(extractors []responseExtractor, response *checkedResponse, data utils.TemplateData) []ExtractResult {
+	if len(extractors) == 0 {
+		return nil
+	}
+
+	vars := data.Vars()
+	results := make([]ExtractResult, len(extractors))
+	for i, extractor := range extractors {
+		value, ok := extractor.extract(response)
+		switch {
+		case ok:
+			vars[extractor.variable] = value
+		case extractor.defaultValue != nil:
+			vars[extractor.variable] = *extractor.defaultValue
+		}
+		results[i] = ExtractResult{Var: extractor.variable, Extracted: ok}
+	}
+	return results
+}
@@ -0,0 +1,77 @@
+package requests
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aykhans/dodo/types"
+	"github.com/aykhans/dodo/utils"
+	"github.com/valyala/fasthttp"
+)
+
+func TestEvaluateExtractors(t *testing.T) {
+	response := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(response)
+	response.Header.Set("X-Token", "abc")
+	response.Header.Set("Set-Cookie", "session=s1; Path=/")
+	response.SetBodyString(`{"id": 7, "name": "dodo", "tags": ["a"], "none": null, "link": "/orders?order=42"}`)
+
+	fallback := "fallback"
+	extractors := newResponseExtractors(types.Extractors{
+		{Var: "id", JSONPath: "id"},
+		{Var: "name", JSONPath: "$.name"},
+		{Var: "tags", JSONPath: "tags"},
+		{Var: "order", Regex: `order=(\d+)`},
+		{Var: "token", Header: "X-Token"},
+		{Var: "session", Cookie: "session"},
+		{Var: "none", JSONPath: "none", Default: &fallback},
+		{Var: "missing", JSONPath: "missing", Default: &fallback},
+		{Var: "kept", Header: "X-Missing"},
+		{Var: "invalid", Regex: "("},
+	})
+
+	data := utils.NewTemplateData()
+	data.Vars()["kept"] = "previous"
+	results := evaluateExtractors(extractors, &checkedResponse{response: response}, data)
+
+	wantResults := []ExtractResult{
+		{Var: "id", Extracted: true},
+		{Var: "name", Extracted: true},
+		{Var: "tags", Extracted: true},
+		{Var: "order", Extracted: true},
+		{Var: "token", Extracted: true},
+		{Var: "session", Extracted: true},
+		{Var: "none", Extracted: false},
+		{Var: "missing", Extracted: false},
+		{Var: "kept", Extracted: false},
+		{Var: "invalid", Extracted: false},
+	}
+	if !reflect.DeepEqual(results, wantResults) {
+		t.Errorf("evaluateExtractors() = %v, want %v", results, wantResults)
+	}
+
+	// Missed values are set to the default if there is one and keep the previous value otherwise.
+	wantVars := map[string]string{
+		"id":      "7",
+		"name":    "dodo",
+		"tags":    `["a"]`,
+		"order":   "42",
+		"token":   "abc",
+		"session": "s1",
+		"none":    "fallback",
+		"missing": "fallback",
+		"kept":    "previous",
+	}
+	if vars := data.Vars(); !reflect.DeepEqual(vars, wantVars) {
+		t.Errorf("vars = %v, want %v", vars, wantVars)
+	}
+}
+
+func TestEvaluateExtractorsWithoutExtractors(t *testing.T) {
+	response := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(response)
+
+	if results := evaluateExtractors(nil, &checkedResponse{response: response}, utils.NewTemplateData()); results != nil {
+		t.Errorf("evaluateExtractors() = %v, want nil", results)
+	}
+}
@@ -3,6 +3,7 @@ package requests
 import (
 	"context"
 	"fmt"
+	"io"
 	"sync"
 	"time"
 
@@ -11,6 +12,9 @@ import (
 
 // streamProgress streams the progress of a task to the console using a progress bar.
 // It listens for increments on the provided channel and updates the progress bar accordingly.
+// Messages received from the messages channel replace the progress bar message;
+// a nil channel keeps the initial message for the whole run.
+// The progress bar is written to the given output.
 //
 // The function will stop and mark the progress as errored if the context is cancelled.
 // It will also stop and mark the progress as done when the total number of increments is reached.
@@ -20,9 +24,12 @@ func streamProgress(
 	total uint,
 	message string,
 	increase <-chan int64,
+	messages <-chan string,
+	output io.Writer,
 ) {
 	defer wg.Done()
 	pw := progress.NewWriter()
+	pw.SetOutputWriter(output)
 	pw.SetTrackerPosition(progress.PositionRight)
 	pw.SetStyle(progress.StyleBlocks)
 	pw.SetTrackerLength(40)
@@ -46,11 +53,14 @@ func streamProgress(
 				dodosTracker.MarkAsErrored()
 			}
 			time.Sleep(time.Millisecond * 300)
-			fmt.Printf("\r")
+			fmt.Fprint(output, "\r")
 			return
 
 		case value := <-increase:
 			dodosTracker.Increment(value)
+
+		case msg := <-messages:
+			dodosTracker.UpdateMessage(msg)
 		}
 	}
 }
@@ -0,0 +1,112 @@
+package requests
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aykhans/dodo/config"
+)
+
+// releaseOpenDodos sends requests using the open model and returns the aggregated result.
+//
+// Unlike the closed model, where each dodo waits for its response before sending the next
+// request, requests are launched on the schedule of the arrival process regardless of the
+// outstanding responses. The dodos form a pool that caps the number of in-flight requests:
+// each launched request borrows an idle dodo and returns it once the response is recorded.
+// If every dodo is busy when a request is due, the request is dropped and counted.
+// With a scenario, each launched request is a whole iteration of it.
+//
+// The launching stops when the context is canceled, when the requests file was replayed
+// to the end or, if a request count is set, once that many requests have been launched.
+func releaseOpenDodos(
+	ctx context.Context,
+	requestConfig *config.RequestConfig,
+	factory *scenarioFactory,
+	outputs statsOutputs,
+) *Result {
+	var (
+		wg         sync.WaitGroup
+		streamWG   sync.WaitGroup
+		stages     = requestConfig.Stages
+		dodosCount = requestConfig.GetValidDodosCountForRequests()
+		stats      = make([]*Stats, dodosCount)
+		scenarios  = make([]*Scenario, dodosCount)
+		idleDodos  = make(chan int, dodosCount)
+		increase   = make(chan int64, requestConfig.RequestCount)
+		messages   = make(chan string, 1)
+		launched   uint
+		dropped    uint64
+	)
+
+	streamWG.Add(1)
+	streamCtx, streamCtxCancel := context.WithCancel(ctx)
+
+	go streamProgress(
+		streamCtx,
+		&streamWG,
+		requestConfig.RequestCount,
+		"Dodos Working🔥",
+		increase,
+		messages,
+		requestConfig.LogWriter(),
+	)
+
+	for i := range dodosCount {
+		scenarios[i] = factory.newScenario(int64(i))
+		stats[i] = newStats(int(requestConfig.HistogramSF), int64(i), outputs)
+		idleDodos <- int(i)
+	}
+
+	limiter := newRateLimiter(streamCtx, float64(requestConfig.Rate), requestConfig.Arrival, requestConfig.Seed)
+	if len(stages) > 0 {
+		go controlStages(streamCtx, stages, messages, func(stageIndex int, target float64) string {
+			limiter.SetRate(target)
+			return rateStageMessage(stageIndex, len(stages), target)
+		})
+	}
+
+	startTime := time.Now()
+
+launching:
+	for requestConfig.RequestCount == 0 || launched < requestConfig.RequestCount {
+		scheduledTime, err := limiter.Wait(ctx)
+		if err != nil {
+			break
+		}
+
+		select {
+		case i := <-idleDodos:
+			requests := scenarios[i].Iteration()
+			if requests == nil {
+				break launching
+			}
+			launched++
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				sendIteration(ctx, requests, requestConfig.Timeout, scheduledTime, stats[i], increase)
+				idleDodos <- i
+			}()
+		default:
+			dropped++
+		}
+	}
+
+	wg.Wait()
+	endTime := time.Now()
+	streamCtxCancel()
+	streamWG.Wait()
+
+	return &Result{
+		Stats:       mergeStats(int(requestConfig.HistogramSF), stats),
+		Percentiles: requestConfig.Percentiles,
+		Series:      outputs.series,
+		StartTime:   startTime,
+		EndTime:     endTime,
+		TargetRate:  requestConfig.Rate,
+		RateLimited: true,
+		Arrival:     requestConfig.Arrival,
+		Dropped:     dropped,
+	}
+}
@@ -0,0 +1,148 @@
+package requests
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/aykhans/dodo/config"
+	"github.com/aykhans/dodo/types"
+)
+
+// rateLimiter hands out send slots to all dodos so that the combined throughput
+// follows the configured requests per second. The gaps between the slots are
+// constant by default, or follow the given arrival process (poisson or uniform).
+// The slots are produced by a single scheduler goroutine, so the rate can be
+// changed while the dodos are running. It is safe for concurrent use.
+type rateLimiter struct {
+	mu          sync.Mutex
+	interval    time.Duration
+	arrival     string
+	localRand   *rand.Rand
+	slots       chan time.Time
+	rateChanged chan struct{}
+}
+
+// newRateLimiter creates a rateLimiter for the given number of requests per second
+// and arrival process, and starts its scheduler, which runs until the context is canceled.
+// The random intervals of the poisson and uniform arrivals are generated from the given seed.
+// A rate of 0 pauses the scheduler until a positive rate is set with SetRate.
+func newRateLimiter(ctx context.Context, rate float64, arrival string, seed int64) *rateLimiter {
+	l := &rateLimiter{
+		arrival:     arrival,
+		localRand:   rand.New(rand.NewSource(seed)),
+		slots:       make(chan time.Time),
+		rateChanged: make(chan struct{}, 1),
+	}
+	l.SetRate(rate)
+	go l.schedule(ctx)
+
+	return l
+}
+
+// SetRate changes the number of requests per second handed out by the limiter.
+// It does nothing if the interval between the slots doesn't change, so that setting
+// the same rate again doesn't disturb the scheduler.
+func (l *rateLimiter) SetRate(rate float64) {
+	interval := time.Duration(0)
+	if rate > 0 {
+		interval = time.Duration(float64(time.Second) / rate)
+	}
+
+	l.mu.Lock()
+	if interval == l.interval {
+		l.mu.Unlock()
+		return
+	}
+	l.interval = interval
+	l.mu.Unlock()
+
+	select {
+	case l.rateChanged <- struct{}{}:
+	default:
+	}
+}
+
+func (l *rateLimiter) getInterval() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.interval
+}
+
+// nextGap returns the gap before the next slot as a multiple of the interval, for the arrival
+// process of the limiter. Poisson arrivals have exponentially distributed gaps and uniform
+// arrivals have gaps spread evenly between 0 and twice the interval, so both keep the mean interval.
+func (l *rateLimiter) nextGap() float64 {
+	switch l.arrival {
+	case config.ArrivalPoisson:
+		return l.localRand.ExpFloat64()
+	case config.ArrivalUniform:
+		return l.localRand.Float64() * 2
+	default:
+		return 1
+	}
+}
+
+// schedule produces the send slots until the context is canceled.
+// Each slot is scheduled one gap after the previous one, even if the previous
+// slot was taken late, so the dodos catch up when they fall behind the target rate.
+// The gap is drawn once per slot as a multiple of the interval, so a rate change
+// scales the pending gap instead of drawing a new one.
+func (l *rateLimiter) schedule(ctx context.Context) {
+	var (
+		last time.Time
+		gap  = l.nextGap()
+	)
+
+	for {
+		interval := l.getInterval()
+		if interval == 0 {
+			last = time.Time{}
+			select {
+			case <-ctx.Done():
+				return
+			case <-l.rateChanged:
+				continue
+			}
+		}
+
+		next := time.Now()
+		if !last.IsZero() {
+			next = last.Add(time.Duration(gap * float64(interval)))
+		}
+
+		if wait := time.Until(next); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-l.rateChanged:
+				timer.Stop()
+				continue
+			case <-timer.C:
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case l.slots <- next:
+			last = next
+			gap = l.nextGap()
+		}
+	}
+}
+
+// Wait blocks until the next slot is available and returns the time the slot was
+// scheduled for, which may be in the past if the dodos are falling behind the target rate.
+// If the context is canceled before a slot is available, it returns types.ErrInterrupt.
+func (l *rateLimiter) Wait(ctx context.Context) (time.Time, error) {
+	select {
+	case <-ctx.Done():
+		return time.Time{}, types.ErrInterrupt
+	case slot := <-l.slots:
+		return slot, nil
+	}
+}
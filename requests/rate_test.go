@@ -0,0 +1,180 @@
+package requests
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/aykhans/dodo/config"
+	"github.com/aykhans/dodo/types"
+)
+
+func TestRateLimiterSlotSpacing(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	limiter := newRateLimiter(ctx, 200, config.ArrivalConstant, 1)
+
+	previous, err := limiter.Wait(ctx)
+	if err != nil {
+		t.Fatalf("Wait() unexpected error: %v", err)
+	}
+	for i := range 10 {
+		slot, err := limiter.Wait(ctx)
+		if err != nil {
+			t.Fatalf("Wait() unexpected error: %v", err)
+		}
+		if gap := slot.Sub(previous); gap != 5*time.Millisecond {
+			t.Errorf("slot %d is %v after the previous one, want 5ms", i+1, gap)
+		}
+		previous = slot
+	}
+}
+
+func TestRateLimiterCatchesUp(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	limiter := newRateLimiter(ctx, 200, config.ArrivalConstant, 1)
+
+	first, err := limiter.Wait(ctx)
+	if err != nil {
+		t.Fatalf("Wait() unexpected error: %v", err)
+	}
+	// The slots missed while the dodos were busy are still handed out at their scheduled times,
+	// so the dodos send them right away instead of dropping them.
+	time.Sleep(30 * time.Millisecond)
+	for i := 1; i <= 5; i++ {
+		slot, err := limiter.Wait(ctx)
+		if err != nil {
+			t.Fatalf("Wait() unexpected error: %v", err)
+		}
+		if want := first.Add(time.Duration(i) * 5 * time.Millisecond); !slot.Equal(want) {
+			t.Errorf("slot %d = %v after the first one, want %v", i, slot.Sub(first), want.Sub(first))
+		}
+		if !slot.Before(time.Now()) {
+			t.Errorf("slot %d is %v after the first one, want it to be in the past", i, slot.Sub(first))
+		}
+	}
+}
+
+func TestRateLimiterWaitInterrupt(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	limiter := newRateLimiter(ctx, 0.001, config.ArrivalConstant, 1)
+	if _, err := limiter.Wait(ctx); err != nil {
+		t.Fatalf("Wait() unexpected error: %v", err)
+	}
+
+	waitCtx, waitCancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer waitCancel()
+	if _, err := limiter.Wait(waitCtx); !errors.Is(err, types.ErrInterrupt) {
+		t.Errorf("Wait() error = %v, want %v", err, types.ErrInterrupt)
+	}
+}
+
+func TestRateLimiterSetRate(t *testing.T) {
+	limiter := &rateLimiter{rateChanged: make(chan struct{}, 1)}
+
+	tests := []struct {
+		rate         float64
+		wantInterval time.Duration
+		wantChanged  bool
+	}{
+		{rate: 100, wantInterval: 10 * time.Millisecond, wantChanged: true},
+		{rate: 100, wantInterval: 10 * time.Millisecond, wantChanged: false},
+		{rate: 100.0000001, wantInterval: 9999999, wantChanged: true},
+		{rate: 50, wantInterval: 20 * time.Millisecond, wantChanged: true},
+		{rate: 0, wantInterval: 0, wantChanged: true},
+		{rate: -1, wantInterval: 0, wantChanged: false},
+	}
+
+	for _, test := range tests {
+		limiter.SetRate(test.rate)
+		if interval := limiter.getInterval(); interval != test.wantInterval {
+			t.Errorf("SetRate(%v) interval = %v, want %v", test.rate, interval, test.wantInterval)
+		}
+		select {
+		case <-limiter.rateChanged:
+			if !test.wantChanged {
+				t.Errorf("SetRate(%v) notified the scheduler, want the unchanged interval to be left alone", test.rate)
+			}
+		default:
+			if test.wantChanged {
+				t.Errorf("SetRate(%v) didn't notify the scheduler of the changed interval", test.rate)
+			}
+		}
+	}
+}
+
+func TestRateLimiterSetRateWhileRunning(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	limiter := newRateLimiter(ctx, 200, config.ArrivalConstant, 1)
+
+	previous, err := limiter.Wait(ctx)
+	if err != nil {
+		t.Fatalf("Wait() unexpected error: %v", err)
+	}
+	limiter.SetRate(50)
+	for i := range 4 {
+		slot, err := limiter.Wait(ctx)
+		if err != nil {
+			t.Fatalf("Wait() unexpected error: %v", err)
+		}
+		// The slot the scheduler was already handing out when the rate changed keeps the old gap.
+		gap := slot.Sub(previous)
+		if gap != 20*time.Millisecond && (i > 0 || gap != 5*time.Millisecond) {
+			t.Errorf("slot %d is %v after the previous one, want 20ms", i+1, gap)
+		}
+		previous = slot
+	}
+}
+
+func TestRateLimiterPausedByZeroRate(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	limiter := newRateLimiter(ctx, 0, config.ArrivalConstant, 1)
+
+	waitCtx, waitCancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer waitCancel()
+	if _, err := limiter.Wait(waitCtx); !errors.Is(err, types.ErrInterrupt) {
+		t.Fatalf("Wait() with a rate of 0 error = %v, want %v", err, types.ErrInterrupt)
+	}
+
+	limiter.SetRate(1000)
+	waitCtx, waitCancel = context.WithTimeout(ctx, time.Second)
+	defer waitCancel()
+	if _, err := limiter.Wait(waitCtx); err != nil {
+		t.Errorf("Wait() after SetRate(1000) unexpected error: %v", err)
+	}
+}
+
+func TestRateLimiterArrivalGaps(t *testing.T) {
+	tests := []struct {
+		arrival string
+		minGap  float64
+		maxGap  float64
+	}{
+		{arrival: config.ArrivalConstant, minGap: 1, maxGap: 1},
+		{arrival: config.ArrivalPoisson, minGap: 0, maxGap: 100},
+		{arrival: config.ArrivalUniform, minGap: 0, maxGap: 2},
+	}
+
+	for _, test := range tests {
+		limiter := &rateLimiter{arrival: test.arrival, localRand: rand.New(rand.NewSource(1))}
+		const count = 100000
+		sum := 0.0
+		for range count {
+			gap := limiter.nextGap()
+			if gap < test.minGap || gap > test.maxGap {
+				t.Fatalf("%s arrival gap = %v, want it between %v and %v", test.arrival, gap, test.minGap, test.maxGap)
+			}
+			sum += gap
+		}
+		// Every arrival process keeps the mean interval, so the configured rate is kept on average.
+		if mean := sum / count; mean < 0.99 || mean > 1.01 {
+			t.Errorf("%s arrival mean gap = %v, want 1", test.arrival, mean)
+		}
+	}
+}
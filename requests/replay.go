@@ -0,0 +1,158 @@
+package requests
+
+import (
+	"math/rand"
+	"net/url"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/aykhans/dodo/config"
+	"github.com/aykhans/dodo/types"
+	"github.com/aykhans/dodo/utils"
+	"github.com/valyala/fasthttp"
+)
+
+// replayRequest is a request of the requests file, prepared to be sent as it is.
+type replayRequest struct {
+	URL     url.URL
+	method  string
+	params  []types.KeyValue[string, string]
+	headers []types.KeyValue[string, string]
+	body    string
+}
+
+// newReplayRequests prepares the requests of the requests file. Only the first value of
+// each param and header, and the first body, are used.
+func newReplayRequests(definitions types.RequestDefinitions) []replayRequest {
+	requests := make([]replayRequest, len(definitions))
+	for i, definition := range definitions {
+		requests[i] = replayRequest{
+			URL:     definition.URL.URL,
+			method:  definition.Method,
+			params:  firstValues(definition.Params),
+			headers: firstValues(definition.Headers),
+		}
+		if len(definition.Body) > 0 {
+			requests[i].body = definition.Body[0]
+		}
+	}
+	return requests
+}
+
+func firstValues[T ~[]types.KeyValue[string, []string]](keyValues T) []types.KeyValue[string, string] {
+	firsts := make([]types.KeyValue[string, string], 0, len(keyValues))
+	for _, keyValue := range keyValues {
+		if len(keyValue.Value) > 0 {
+			firsts = append(firsts, types.KeyValue[string, string]{Key: keyValue.Key, Value: keyValue.Value[0]})
+		}
+	}
+	return firsts
+}
+
+// replayCursor picks the requests of the requests file to replay in the configured order.
+// The position of the sequential and round-robin orders is shared by all dodos, so each request
+// is replayed by one of them; the random order picks with the random generator of each dodo.
+type replayCursor struct {
+	order    string
+	count    uint64
+	position atomic.Uint64
+}
+
+func newReplayCursor(order string, count int) *replayCursor {
+	return &replayCursor{order: order, count: uint64(count)}
+}
+
+// next returns the index of the next request to replay, or false once all requests
+// were replayed in sequential order.
+func (cursor *replayCursor) next(localRand *rand.Rand) (int, bool) {
+	switch cursor.order {
+	case config.RequestsOrderRandom:
+		return localRand.Intn(int(cursor.count)), true
+	case config.RequestsOrderRoundRobin:
+		return int((cursor.position.Add(1) - 1) % cursor.count), true
+	default:
+		position := cursor.position.Add(1) - 1
+		if position >= cursor.count {
+			return 0, false
+		}
+		return int(position), true
+	}
+}
+
+// getReplayIterationFunc returns a function that returns the next request of the requests
+// file to replay as an iteration of a single request, or nil once the requests are exhausted.
+// The requests are sent with the clients of their hosts and their responses are evaluated with
+// the top-level checks and extractors, whose values are set in the given template data.
+// Like the requests of a mix, they get the top-level vars, params, headers and cookies of the
+// request config, executed as templates with the given template data, except for the params
+// and headers they set themselves.
+func getReplayIterationFunc(
+	requests []replayRequest,
+	cursor *replayCursor,
+	clients clientPool,
+	requestConfig *config.RequestConfig,
+	localRand *rand.Rand,
+	data utils.TemplateData,
+	sequence *requestSequence,
+) func() []*Request {
+	getClients := make(map[string]ClientGeneratorFunc, len(clients))
+	for key, hostClients := range clients {
+		if len(hostClients) == 1 {
+			getClients[key] = getSharedClientFuncSingle(hostClients[0])
+		} else {
+			getClients[key] = getSharedClientFuncMultiple(hostClients, localRand)
+		}
+	}
+	checks := newResponseChecks(requestConfig.Checks)
+	extractors := newResponseExtractors(requestConfig.Extract)
+	setVars := getVarsSetterFunc(requestConfig.Vars, localRand, data)
+	getParams := getKeyValueGeneratorFunc(requestConfig.Params, localRand, data)
+	getHeaders := getKeyValueGeneratorFunc(requestConfig.Headers, localRand, data)
+	getCookies := getKeyValueGeneratorFunc(requestConfig.Cookies, localRand, data)
+
+	return func() []*Request {
+		index, ok := cursor.next(localRand)
+		if !ok {
+			return nil
+		}
+
+		replayed := &requests[index]
+		return []*Request{{
+			getClient: getClients[clientPoolKey(replayed.URL)],
+			getRequest: func() *fasthttp.Request {
+				data["seq"], data["elapsed"] = sequence.next()
+				setVars()
+				return newFasthttpRequest(
+					replayed.URL,
+					withDefaultValues(getParams(), replayed.params, func(a, b string) bool { return a == b }),
+					withDefaultValues(getHeaders(), replayed.headers, strings.EqualFold),
+					getCookies(),
+					replayed.method,
+					replayed.body,
+				)
+			},
+			checks:     checks,
+			extractors: extractors,
+			data:       data,
+		}}
+	}
+}
+
+// withDefaultValues returns the values after the defaults whose keys aren't among them,
+// comparing the keys with equal.
+func withDefaultValues(
+	defaults []types.KeyValue[string, string],
+	values []types.KeyValue[string, string],
+	equal func(a, b string) bool,
+) []types.KeyValue[string, string] {
+	merged := make([]types.KeyValue[string, string], 0, len(defaults)+len(values))
+	for _, defaultValue := range defaults {
+		if !slices.ContainsFunc(values, func(value types.KeyValue[string, string]) bool {
+			return equal(value.Key, defaultValue.Key)
+		}) {
+			merged = append(merged, defaultValue)
+		}
+	}
+	return append(merged, values...)
+}
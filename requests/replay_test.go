@@ -0,0 +1,107 @@
+package requests
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aykhans/dodo/config"
+	"github.com/aykhans/dodo/types"
+)
+
+func TestReplayCursorSequentialExhaustion(t *testing.T) {
+	const count = 100
+	cursor := newReplayCursor(config.RequestsOrderSequential, count)
+
+	// The dodos share the position, so every request is replayed exactly once over all of them.
+	var (
+		mu       sync.Mutex
+		replayed = make(map[int]int)
+		wg       sync.WaitGroup
+	)
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				index, ok := cursor.next(nil)
+				if !ok {
+					return
+				}
+				mu.Lock()
+				replayed[index]++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(replayed) != count {
+		t.Errorf("replayed %d requests, want %d", len(replayed), count)
+	}
+	for index, times := range replayed {
+		if index < 0 || index >= count || times != 1 {
+			t.Errorf("request %d replayed %d times, want once", index, times)
+		}
+	}
+	if _, ok := cursor.next(nil); ok {
+		t.Errorf("next() after the last request = true, want false")
+	}
+}
+
+func TestReplayCursorRoundRobin(t *testing.T) {
+	cursor := newReplayCursor(config.RequestsOrderRoundRobin, 3)
+	var got []int
+	for range 7 {
+		index, ok := cursor.next(nil)
+		if !ok {
+			t.Fatalf("next() = false, want the round-robin order to wrap around")
+		}
+		got = append(got, index)
+	}
+	if want := []int{0, 1, 2, 0, 1, 2, 0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("indexes = %v, want %v", got, want)
+	}
+}
+
+func TestReplayCursorRandom(t *testing.T) {
+	cursor := newReplayCursor(config.RequestsOrderRandom, 3)
+	localRand := rand.New(rand.NewSource(1))
+	seen := make(map[int]bool)
+	for range 100 {
+		index, ok := cursor.next(localRand)
+		if !ok || index < 0 || index >= 3 {
+			t.Fatalf("next() = %d, %v, want an index below 3", index, ok)
+		}
+		seen[index] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("random order picked %v, want all 3 requests", seen)
+	}
+}
+
+func TestWithDefaultValues(t *testing.T) {
+	defaults := []types.KeyValue[string, string]{{Key: "Accept", Value: "*/*"}, {Key: "X-Run", Value: "1"}, {Key: "X-Run", Value: "2"}}
+	values := []types.KeyValue[string, string]{{Key: "accept", Value: "text/html"}, {Key: "X-Id", Value: "7"}}
+
+	// The defaults are left out when the replayed request has the key, whatever the case of header names.
+	got := withDefaultValues(defaults, values, strings.EqualFold)
+	want := []types.KeyValue[string, string]{
+		{Key: "X-Run", Value: "1"},
+		{Key: "X-Run", Value: "2"},
+		{Key: "accept", Value: "text/html"},
+		{Key: "X-Id", Value: "7"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("withDefaultValues() = %v, want %v", got, want)
+	}
+
+	// Param names are case sensitive.
+	got = withDefaultValues(defaults, values, func(a, b string) bool { return a == b })
+	want = append(defaults[:1:1], want...)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("withDefaultValues() = %v, want %v", got, want)
+	}
+}
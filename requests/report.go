@@ -0,0 +1,319 @@
+package requests
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/aykhans/dodo/config"
+	"github.com/aykhans/dodo/types"
+)
+
+// ReportSchemaVersion is the version of the JSON report document.
+// It is increased on every change that could break existing parsers of the report,
+// such as renaming or removing a field; adding a field doesn't change it.
+const ReportSchemaVersion = 1
+
+// Report is the machine-readable form of a run result.
+// All durations are in milliseconds and all rates are per second.
+type Report struct {
+	SchemaVersion    int                    `json:"schema_version"`
+	DodoVersion      string                 `json:"dodo_version"`
+	Config           *config.Config         `json:"config"`
+	StartTime        time.Time              `json:"start_time"`
+	EndTime          time.Time              `json:"end_time"`
+	ElapsedMs        float64                `json:"elapsed_ms"`
+	TargetRate       uint                   `json:"target_rate,omitempty"`
+	Dropped          *uint64                `json:"dropped,omitempty"`
+	ReplaySpeed      float64                `json:"replay_speed,omitempty"`
+	Drift            *ReportLatency         `json:"drift,omitempty"`
+	Total            ReportResponses        `json:"total"`
+	Responses        []ReportResponses      `json:"responses"`
+	StatusGroups     []ReportResponses      `json:"status_groups"`
+	Errors           ReportErrors           `json:"errors"`
+	CorrectedLatency *ReportLatency         `json:"corrected_latency,omitempty"`
+	Phases           map[string]ReportPhase `json:"phases"`
+	Steps            []ReportStep           `json:"steps,omitempty"`
+	Endpoints        []ReportStep           `json:"endpoints,omitempty"`
+	Iterations       *ReportPhase           `json:"iterations,omitempty"`
+	Series           *ReportSeries          `json:"series,omitempty"`
+	Checks           *ReportChecks          `json:"checks,omitempty"`
+	Extractions      []ReportExtraction     `json:"extractions,omitempty"`
+	Thresholds       []ReportThreshold      `json:"thresholds,omitempty"`
+}
+
+// ReportResponses summarizes the responses of a category, status group or of the whole run.
+type ReportResponses struct {
+	Name                string        `json:"name"`
+	Count               uint64        `json:"count"`
+	Rate                float64       `json:"rate"`
+	BytesSent           uint64        `json:"bytes_sent"`
+	BytesReceived       uint64        `json:"bytes_received"`
+	AverageResponseSize uint64        `json:"average_response_size"`
+	Latency             ReportLatency `json:"latency"`
+}
+
+// ReportLatency summarizes a latency histogram.
+// Percentiles are keyed by their column names (e.g. "P99.9").
+type ReportLatency struct {
+	MinMs       float64            `json:"min_ms"`
+	MaxMs       float64            `json:"max_ms"`
+	MeanMs      float64            `json:"mean_ms"`
+	Percentiles map[string]float64 `json:"percentiles_ms"`
+}
+
+// ReportErrors counts the requests that failed or got a 4xx or 5xx response.
+// Ratio is the share of those requests among all requests.
+type ReportErrors struct {
+	Count uint64  `json:"count"`
+	Ratio float64 `json:"ratio"`
+}
+
+// ReportPhase summarizes the time spent in one phase of the requests.
+type ReportPhase struct {
+	Count   uint64        `json:"count"`
+	Latency ReportLatency `json:"latency"`
+}
+
+// ReportStep summarizes the responses of a scenario step or an endpoint of the mix.
+type ReportStep struct {
+	Name      string            `json:"name"`
+	Total     ReportResponses   `json:"total"`
+	Responses []ReportResponses `json:"responses"`
+}
+
+// newReportSteps returns the summaries of the named requests in the given order,
+// using newResponses to summarize their responses.
+// It returns nil if there are no names.
+func newReportSteps(
+	names []string,
+	stats *Stats,
+	newResponses func(name string, responseStats *ResponseStats) ReportResponses,
+) []ReportStep {
+	var steps []ReportStep
+	for _, name := range names {
+		step := ReportStep{Name: name, Responses: []ReportResponses{}}
+		if stepStats := stats.Step(name); stepStats != nil {
+			step.Total = newResponses("Total", stepStats.Total())
+			for _, category := range stepStats.Categories() {
+				step.Responses = append(step.Responses, newResponses(category, stepStats.Category(category)))
+			}
+		} else {
+			step.Total = newResponses("Total", newResponseStats(stats.significantFigures))
+		}
+		steps = append(steps, step)
+	}
+	return steps
+}
+
+// ReportSeries holds the time series of the run.
+type ReportSeries struct {
+	IntervalMs float64          `json:"interval_ms"`
+	Intervals  []ReportInterval `json:"intervals"`
+}
+
+// ReportInterval summarizes the requests completed during one interval of the time series.
+// StartMs is the start of the interval relative to the start of the run.
+type ReportInterval struct {
+	StartMs  float64       `json:"start_ms"`
+	Requests uint64        `json:"requests"`
+	Rate     float64       `json:"rate"`
+	Errors   uint64        `json:"errors"`
+	Latency  ReportLatency `json:"latency"`
+}
+
+// ReportChecks counts the responses the checks were evaluated on and the ones that failed
+// any check, with the outcomes of each check. Ratio is the share of the failed responses
+// among the checked ones.
+type ReportChecks struct {
+	Checked uint64        `json:"checked"`
+	Failed  uint64        `json:"failed"`
+	Ratio   float64       `json:"ratio"`
+	Checks  []ReportCheck `json:"checks"`
+}
+
+// ReportCheck counts the responses that passed and failed a check.
+type ReportCheck struct {
+	Name   string `json:"name"`
+	Passed uint64 `json:"passed"`
+	Failed uint64 `json:"failed"`
+}
+
+// ReportExtraction counts the responses a variable was extracted from and the ones it was missing from.
+type ReportExtraction struct {
+	Var       string `json:"var"`
+	Extracted uint64 `json:"extracted"`
+	Missed    uint64 `json:"missed"`
+}
+
+// ReportThreshold is the outcome of a threshold. Actual is in the unit of the threshold's metric:
+// milliseconds for latencies, a ratio between 0 and 1 for error_rate and ratio, and a number otherwise.
+type ReportThreshold struct {
+	Threshold string  `json:"threshold"`
+	Actual    float64 `json:"actual"`
+	Passed    bool    `json:"passed"`
+}
+
+// NewReport creates the report of the result. The given config is included as the
+// resolved config of the run, in the same format as the config file.
+func NewReport(result *Result, conf *config.Config) *Report {
+	stats := result.Stats
+	elapsed := result.Elapsed()
+
+	newResponses := func(name string, responseStats *ResponseStats) ReportResponses {
+		return ReportResponses{
+			Name:                name,
+			Count:               responseStats.Count(),
+			Rate:                perSecond(responseStats.Count(), elapsed),
+			BytesSent:           responseStats.BytesSent,
+			BytesReceived:       responseStats.BytesReceived,
+			AverageResponseSize: responseStats.AverageResponseSize(),
+			Latency:             newReportLatency(responseStats.Latency, result.Percentiles),
+		}
+	}
+
+	total := stats.Total()
+	report := &Report{
+		SchemaVersion: ReportSchemaVersion,
+		DodoVersion:   config.VERSION,
+		Config:        conf,
+		StartTime:     result.StartTime,
+		EndTime:       result.EndTime,
+		ElapsedMs:     milliseconds(elapsed),
+		TargetRate:    result.TargetRate,
+		Total:         newResponses("Total", total),
+		Responses:     []ReportResponses{},
+		StatusGroups:  []ReportResponses{},
+		Phases:        make(map[string]ReportPhase),
+	}
+	if result.Arrival != "" {
+		report.Dropped = &result.Dropped
+	}
+	if result.Drift != nil {
+		drift := newReportLatency(result.Drift, result.Percentiles)
+		report.ReplaySpeed = result.ReplaySpeed
+		report.Drift = &drift
+	}
+
+	for _, category := range stats.Categories() {
+		report.Responses = append(report.Responses, newResponses(category, stats.Category(category)))
+	}
+	report.Errors.Count = stats.ErrorCount()
+	if total.Count() > 0 {
+		report.Errors.Ratio = float64(report.Errors.Count) / float64(total.Count())
+	}
+
+	groupNames, groups := stats.StatusGroups()
+	for _, group := range groupNames {
+		report.StatusGroups = append(report.StatusGroups, newResponses(group, groups[group]))
+	}
+
+	if result.RateLimited {
+		correctedLatency := newReportLatency(total.CorrectedLatency, result.Percentiles)
+		report.CorrectedLatency = &correctedLatency
+	}
+
+	for _, phase := range stats.Phases().all() {
+		report.Phases[phase.name] = ReportPhase{
+			Count:   phase.histogram.Count(),
+			Latency: newReportLatency(phase.histogram, result.Percentiles),
+		}
+	}
+
+	report.Steps = newReportSteps(result.Steps, stats, newResponses)
+	report.Endpoints = newReportSteps(result.Endpoints, stats, newResponses)
+	if len(result.Steps) > 0 {
+		report.Iterations = &ReportPhase{
+			Count:   stats.Iterations().Count(),
+			Latency: newReportLatency(stats.Iterations(), result.Percentiles),
+		}
+	}
+
+	if series := result.Series; series != nil {
+		rates := series.Rates()
+		report.Series = &ReportSeries{
+			IntervalMs: milliseconds(series.Interval()),
+			Intervals:  make([]ReportInterval, 0, len(rates)),
+		}
+		for i, interval := range series.Intervals() {
+			report.Series.Intervals = append(report.Series.Intervals, ReportInterval{
+				StartMs:  milliseconds(time.Duration(i) * series.Interval()),
+				Requests: interval.Requests,
+				Rate:     rates[i],
+				Errors:   interval.Errors,
+				Latency:  newReportLatency(interval.Latency, result.Percentiles),
+			})
+		}
+	}
+
+	if checks := stats.Checks(); len(checks) > 0 {
+		report.Checks = &ReportChecks{
+			Checked: stats.CheckedResponses(),
+			Failed:  stats.FailedChecks(),
+			Ratio:   float64(stats.FailedChecks()) / float64(stats.CheckedResponses()),
+			Checks:  make([]ReportCheck, 0, len(checks)),
+		}
+		for _, name := range checks {
+			checkStats := stats.Check(name)
+			report.Checks.Checks = append(report.Checks.Checks, ReportCheck{
+				Name:   name,
+				Passed: checkStats.Passed,
+				Failed: checkStats.Failed,
+			})
+		}
+	}
+
+	for _, variable := range stats.Extractions() {
+		extractStats := stats.Extraction(variable)
+		report.Extractions = append(report.Extractions, ReportExtraction{
+			Var:       variable,
+			Extracted: extractStats.Extracted,
+			Missed:    extractStats.Missed,
+		})
+	}
+
+	for _, thresholdResult := range result.Thresholds {
+		actual := thresholdResult.Actual
+		if thresholdResult.Threshold.IsLatency() {
+			actual = milliseconds(time.Duration(actual))
+		}
+		report.Thresholds = append(report.Thresholds, ReportThreshold{
+			Threshold: thresholdResult.Threshold.String(),
+			Actual:    actual,
+			Passed:    thresholdResult.Passed,
+		})
+	}
+
+	return report
+}
+
+// WriteJSON writes the report to w as an indented JSON document.
+func (report *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+func newReportLatency(histogram *types.Histogram, percentiles types.Percentiles) ReportLatency {
+	latency := ReportLatency{
+		MinMs:       milliseconds(histogram.Min()),
+		MaxMs:       milliseconds(histogram.Max()),
+		MeanMs:      milliseconds(histogram.Mean()),
+		Percentiles: make(map[string]float64, len(percentiles)),
+	}
+	for _, percentile := range percentiles {
+		latency.Percentiles[types.FormatPercentile(percentile)] = milliseconds(histogram.Percentile(percentile))
+	}
+	return latency
+}
+
+func milliseconds(duration time.Duration) float64 {
+	return float64(duration) / float64(time.Millisecond)
+}
+
+func perSecond(count uint64, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(count) / elapsed.Seconds()
+}
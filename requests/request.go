@@ -5,6 +5,7 @@ import (
 	"context"
 	"math/rand"
 	"net/url"
+	"sync/atomic"
 	"text/template"
 	"time"
 
@@ -17,10 +18,90 @@ import (
 type RequestGeneratorFunc func() *fasthttp.Request
 
 // Request represents an HTTP request to be sent using the fasthttp client.
+// Name is the name of the scenario step the request belongs to, empty outside of scenarios.
+// Data is the template data of the dodo, shared by all requests of its scenario.
 // It isn't thread-safe and should be used by a single goroutine.
 type Request struct {
+	name       string
 	getClient  ClientGeneratorFunc
 	getRequest RequestGeneratorFunc
+	checks     []responseCheck
+	extractors []responseExtractor
+	data       utils.TemplateData
+}
+
+// Scenario holds the requests a dodo sends in every iteration: the steps of the configured
+// scenario in order, one request sampled from the weighted mix, one request of the requests
+// file, or the single top-level request of the config.
+// If a data file is configured, the row of each iteration is set in the template data of the dodo,
+// together with the number of the iteration.
+// It isn't thread-safe and should be used by a single goroutine.
+type Scenario struct {
+	requests  []*Request
+	next      func() []*Request
+	data      utils.TemplateData
+	nextRow   func() (map[string]any, bool)
+	iteration int
+}
+
+// Iteration returns the requests to send in the next iteration, or nil if the
+// requests file was replayed to the end or the rows of the data file were used up.
+func (s *Scenario) Iteration() []*Request {
+	if s.nextRow != nil {
+		row, ok := s.nextRow()
+		if !ok {
+			return nil
+		}
+		s.data["row"] = row
+	}
+	if s.data != nil {
+		s.data["iter"] = s.iteration
+		s.iteration++
+	}
+	if s.next != nil {
+		return s.next()
+	}
+	return s.requests
+}
+
+// scenarioFactory creates the scenarios of the dodos of a run, which share the clients,
+// the positions in the requests file and in the data file, and the run's sequence of requests.
+type scenarioFactory struct {
+	requestConfig *config.RequestConfig
+	clients       clientPool
+	replay        []replayRequest
+	replayCursor  *replayCursor
+	dataCursor    *dataCursor
+	sequence      *requestSequence
+}
+
+// requestSequence numbers the requests created by all dodos of a run and
+// measures the time since the start of the run for their templates.
+type requestSequence struct {
+	startTime time.Time
+	count     atomic.Int64
+}
+
+// next returns the number of the next request of the run, starting from 0,
+// and the time elapsed since the start of the run.
+func (sequence *requestSequence) next() (int, time.Duration) {
+	return int(sequence.count.Add(1) - 1), time.Since(sequence.startTime)
+}
+
+func newScenarioFactory(requestConfig *config.RequestConfig, clients clientPool) *scenarioFactory {
+	factory := &scenarioFactory{
+		requestConfig: requestConfig,
+		clients:       clients,
+		sequence:      &requestSequence{startTime: time.Now()},
+	}
+	if len(requestConfig.Replay) > 0 {
+		factory.replay = newReplayRequests(requestConfig.Replay)
+		factory.replayCursor = newReplayCursor(requestConfig.RequestsOrder, len(factory.replay))
+	}
+	if len(requestConfig.DataRows) > 0 {
+		factory.dataCursor = newDataCursor(requestConfig.Data.Mode, requestConfig.DataRows)
+	}
+	return factory
 }
 
 type keyValueGenerator struct {
@@ -29,44 +110,129 @@ type keyValueGenerator struct {
 }
 
 // Send sends the HTTP request using the fasthttp client with a specified timeout.
-// It returns the HTTP response or an error if the request fails or times out.
-func (r *Request) Send(ctx context.Context, timeout time.Duration) (*fasthttp.Response, error) {
+// It returns the HTTP response and the trace of the request (phase durations and bytes),
+// or an error if the request fails or times out. The proxy of the trace is set in both cases.
+func (r *Request) Send(ctx context.Context, timeout time.Duration) (*fasthttp.Response, Trace, error) {
 	client := r.getClient()
 	request := r.getRequest()
-	defer fasthttp.ReleaseRequest(request)
-
 	response := fasthttp.AcquireResponse()
-	ch := make(chan error)
+
+	trace := &Trace{}
+	proxy := ""
+	untrace := func() {}
+	if transport, ok := client.Transport.(*tracingTransport); ok {
+		untrace = transport.trace(request, trace)
+		proxy = transport.proxy
+	}
+
+	ch := make(chan error, 1)
 	go func() {
 		err := client.DoTimeout(request, response, timeout)
 		ch <- err
 	}()
+	// If the request is given up on before it's done, it's still in use, so it's released once it's done.
+	release := func(releaseResponse bool) {
+		untrace()
+		fasthttp.ReleaseRequest(request)
+		if releaseResponse {
+			fasthttp.ReleaseResponse(response)
+		}
+	}
 	select {
 	case err := <-ch:
+		release(err != nil)
 		if err != nil {
-			fasthttp.ReleaseResponse(response)
-			return nil, err
+			return nil, Trace{Proxy: proxy}, err
 		}
-		return response, nil
+		trace.Proxy = proxy
+		return response, *trace, nil
 	case <-time.After(timeout):
-		fasthttp.ReleaseResponse(response)
-		return nil, types.ErrTimeout
+		go func() { <-ch; release(true) }()
+		return nil, Trace{Proxy: proxy}, types.ErrTimeout
 	case <-ctx.Done():
-		return nil, types.ErrInterrupt
+		go func() { <-ch; release(true) }()
+		return nil, Trace{Proxy: proxy}, types.ErrInterrupt
 	}
 }
 
-// newRequest creates a new Request instance based on the provided configuration and clients.
-// It initializes a random number generator using the current time and a unique identifier (uid).
+// Check evaluates the checks of the request on the response and captures the values of
+// its extractors into the variables of the template data. It returns the outcomes of the
+// checks and of the extractions, each nil if the request has none.
+func (r *Request) Check(response *fasthttp.Response) ([]CheckResult, []ExtractResult) {
+	if len(r.checks) == 0 && len(r.extractors) == 0 {
+		return nil, nil
+	}
+
+	checked := &checkedResponse{response: response}
+	return evaluateChecks(r.checks, checked), evaluateExtractors(r.extractors, checked, r.data)
+}
+
+// newScenario creates the Scenario of a dodo based on the configuration and clients of the factory.
+// It initializes a random number generator using the seed of the run and a unique identifier (uid),
+// so that the random values of each dodo are repeated by a run with the same seed.
+// The generator is shared by all requests of the scenario together with the template data.
+// For a weighted mix, the requests of the iterations are sampled with the same generator.
+// The rows of the data file are picked for the iterations with the uid and the same generator.
+// The requests of a requests file are sent as they are, only evaluated with the top-level checks and extractors.
+func (factory *scenarioFactory) newScenario(uid int64) *Scenario {
+	var (
+		requestConfig = factory.requestConfig
+		clients       = factory.clients
+		localRand     = rand.New(rand.NewSource(requestConfig.Seed + uid))
+		data          = utils.NewTemplateData()
+	)
+	data["dodo"] = int(uid)
+
+	if factory.replay != nil {
+		return &Scenario{
+			next: getReplayIterationFunc(
+				factory.replay,
+				factory.replayCursor,
+				clients,
+				requestConfig,
+				localRand,
+				data,
+				factory.sequence,
+			),
+			data: data,
+		}
+	}
+
+	definitions := requestConfig.GetRequestDefinitions()
+	scenario := &Scenario{requests: make([]*Request, len(definitions)), data: data}
+	if factory.dataCursor != nil {
+		scenario.nextRow = func() (map[string]any, bool) {
+			return factory.dataCursor.next(uid, localRand)
+		}
+	}
+	for i, definition := range definitions {
+		scenario.requests[i] = newRequest(definition, clients.get(definition.URL.URL), localRand, data, factory.sequence)
+	}
+
+	if len(requestConfig.Mix) > 0 {
+		iterations := make([][]*Request, len(scenario.requests))
+		weights := make([]uint, len(definitions))
+		for i, definition := range definitions {
+			iterations[i] = scenario.requests[i : i+1]
+			weights[i] = definition.GetWeight()
+		}
+		scenario.next = utils.WeightedRandomValue(iterations, weights, localRand)
+	}
+	return scenario
+}
+
+// newRequest creates a new Request instance based on the provided request definition and clients.
 // Depending on the number of clients provided, it sets up a function to select the appropriate client.
-// It also sets up a function to generate the request based on the provided configuration.
+// It also sets up a function to generate the request based on the provided definition,
+// whose templates are executed with the given template data and numbered with the given sequence,
+// and prepares the checks and extractors of its responses.
 func newRequest(
-	requestConfig config.RequestConfig,
+	definition types.RequestDefinition,
 	clients []*fasthttp.HostClient,
-	uid int64,
+	localRand *rand.Rand,
+	data utils.TemplateData,
+	sequence *requestSequence,
 ) *Request {
-	localRand := rand.New(rand.NewSource(time.Now().UnixNano() + uid))
-
 	clientsCount := len(clients)
 	if clientsCount < 1 {
 		panic("no clients")
@@ -80,18 +246,25 @@ func newRequest(
 	}
 
 	getRequest := getRequestGeneratorFunc(
-		requestConfig.URL,
-		requestConfig.Params,
-		requestConfig.Headers,
-		requestConfig.Cookies,
-		requestConfig.Method,
-		requestConfig.Body,
+		definition.URL.URL,
+		definition.Vars,
+		definition.Params,
+		definition.Headers,
+		definition.Cookies,
+		definition.Method,
+		definition.Body,
 		localRand,
+		data,
+		sequence,
 	)
 
 	requests := &Request{
+		name:       definition.Name,
 		getClient:  getClient,
 		getRequest: getRequest,
+		checks:     newResponseChecks(definition.Checks),
+		extractors: newResponseExtractors(definition.Extract),
+		data:       data,
 	}
 
 	return requests
@@ -99,22 +272,33 @@ func newRequest(
 
 // getRequestGeneratorFunc returns a RequestGeneratorFunc which generates HTTP requests with the specified parameters.
 // The function uses a local random number generator to select bodies, headers, cookies, and parameters if multiple options are provided.
+// Their templates are executed with the given template data, after the number of the request in the sequence,
+// the elapsed time of the run and the vars of the request are set in it. The body is rendered first and set
+// in the template data, so that the templates of the headers, params and cookies can use it.
 func getRequestGeneratorFunc(
 	URL url.URL,
+	vars types.Vars,
 	params types.Params,
 	headers types.Headers,
 	cookies types.Cookies,
 	method string,
 	bodies []string,
 	localRand *rand.Rand,
+	data utils.TemplateData,
+	sequence *requestSequence,
 ) RequestGeneratorFunc {
-	getParams := getKeyValueGeneratorFunc(params, localRand)
-	getHeaders := getKeyValueGeneratorFunc(headers, localRand)
-	getCookies := getKeyValueGeneratorFunc(cookies, localRand)
-	getBody := getBodyValueFunc(bodies, utils.NewFuncMapGenerator(localRand), localRand)
+	getParams := getKeyValueGeneratorFunc(params, localRand, data)
+	getHeaders := getKeyValueGeneratorFunc(headers, localRand, data)
+	getCookies := getKeyValueGeneratorFunc(cookies, localRand, data)
+	getBody := getBodyValueFunc(bodies, utils.NewFuncMapGenerator(localRand), localRand, data)
+	setVars := getVarsSetterFunc(vars, localRand, data)
 
 	return func() *fasthttp.Request {
+		data["seq"], data["elapsed"] = sequence.next()
+		setVars()
+		data["body"] = ""
 		body, contentType := getBody()
+		data["body"] = body
 		headers := getHeaders()
 		if contentType != "" {
 			headers = append(headers, types.KeyValue[string, string]{
@@ -145,7 +329,7 @@ func newFasthttpRequest(
 	body string,
 ) *fasthttp.Request {
 	request := fasthttp.AcquireRequest()
-	request.SetRequestURI(URL.Path)
+	request.SetRequestURI(URL.EscapedPath())
 
 	// Set the host of the request to the host header
 	// If the host header is not set, the request will fail
@@ -197,7 +381,7 @@ func setRequestBody(req *fasthttp.Request, body string) {
 
 // getKeyValueGeneratorFunc creates a function that generates key-value pairs for HTTP requests.
 // It takes a slice of key-value pairs where each key maps to a slice of possible values,
-// and a random number generator.
+// a random number generator and the data the templates are executed with.
 //
 // If any key has multiple possible values, the function will randomly select one value for each
 // call (using the provided random number generator). If all keys have at most one value, the
@@ -207,6 +391,7 @@ func getKeyValueGeneratorFunc[
 ](
 	keyValueSlice []types.KeyValue[string, []string],
 	localRand *rand.Rand,
+	data utils.TemplateData,
 ) func() T {
 	keyValueGenerators := make([]keyValueGenerator, len(keyValueSlice))
 
@@ -214,8 +399,8 @@ func getKeyValueGeneratorFunc[
 
 	for i, kv := range keyValueSlice {
 		keyValueGenerators[i] = keyValueGenerator{
-			key:   getKeyFunc(kv.Key, funcMap),
-			value: getValueFunc(kv.Value, funcMap, localRand),
+			key:   getKeyFunc(kv.Key, funcMap, data),
+			value: getValueFunc(kv.Value, funcMap, localRand, data),
 		}
 	}
 
@@ -231,15 +416,39 @@ func getKeyValueGeneratorFunc[
 	}
 }
 
+// getVarsSetterFunc creates a function that evaluates the vars of a request into the variables
+// of the template data, so that all parts of the request see the same values.
+// The vars are evaluated in order, so a var can use the ones defined before it, and like
+// extracted variables they are kept for the following requests of the dodo until overwritten.
+func getVarsSetterFunc(vars types.Vars, localRand *rand.Rand, data utils.TemplateData) func() {
+	if len(vars) == 0 {
+		return func() {}
+	}
+
+	funcMap := *utils.NewFuncMapGenerator(localRand).GetFuncMap()
+
+	values := make([]func() string, len(vars))
+	for i, variable := range vars {
+		values[i] = getValueFunc(variable.Value, funcMap, localRand, data)
+	}
+
+	return func() {
+		for i, variable := range vars {
+			data.Vars()[variable.Key] = values[i]()
+		}
+	}
+}
+
 // getKeyFunc creates a function that processes a key string through Go's template engine.
-// It takes a key string and a template.FuncMap containing the available template functions.
+// It takes a key string, a template.FuncMap containing the available template functions
+// and the data the template is executed with.
 //
 // The returned function, when called, will execute the template with the given key and return
 // the processed string result. If template parsing fails, the returned function will always
 // return an empty string.
 //
 // This enables dynamic generation of keys that can include template directives and functions.
-func getKeyFunc(key string, funcMap template.FuncMap) func() string {
+func getKeyFunc(key string, funcMap template.FuncMap, data utils.TemplateData) func() string {
 	t, err := template.New("default").Funcs(funcMap).Parse(key)
 	if err != nil {
 		return func() string { return "" }
@@ -247,7 +456,7 @@ func getKeyFunc(key string, funcMap template.FuncMap) func() string {
 
 	return func() string {
 		var buf bytes.Buffer
-		_ = t.Execute(&buf, nil)
+		_ = t.Execute(&buf, data)
 		return buf.String()
 	}
 }
@@ -259,6 +468,7 @@ func getKeyFunc(key string, funcMap template.FuncMap) func() string {
 //   - values: A slice of string templates that can contain template directives
 //   - funcMap: A template.FuncMap containing all available template functions
 //   - localRand: A random number generator for consistent randomization
+//   - data: The data the templates are executed with
 //
 // The returned function, when called, will:
 //  1. Select a random template from the values slice
@@ -271,6 +481,7 @@ func getValueFunc(
 	values []string,
 	funcMap template.FuncMap,
 	localRand *rand.Rand,
+	data utils.TemplateData,
 ) func() string {
 	templates := make([]*template.Template, len(values))
 
@@ -289,7 +500,7 @@ func getValueFunc(
 			return ""
 		} else {
 			var buf bytes.Buffer
-			_ = tmpl.Execute(&buf, nil)
+			_ = tmpl.Execute(&buf, data)
 			return buf.String()
 		}
 	}
@@ -302,6 +513,7 @@ func getValueFunc(
 //   - values: A slice of string templates that can contain template directives for request bodies
 //   - funcMapGenerator: Provides template functions and content type information
 //   - localRand: A random number generator for consistent randomization
+//   - data: The data the templates are executed with
 //
 // The returned function, when called, will:
 //  1. Select a random body template from the values slice
@@ -316,6 +528,7 @@ func getBodyValueFunc(
 	values []string,
 	funcMapGenerator *utils.FuncMapGenerator,
 	localRand *rand.Rand,
+	data utils.TemplateData,
 ) func() (string, string) {
 	templates := make([]*template.Template, len(values))
 
@@ -334,7 +547,7 @@ func getBodyValueFunc(
 			return "", ""
 		} else {
 			var buf bytes.Buffer
-			_ = tmpl.Execute(&buf, nil)
+			_ = tmpl.Execute(&buf, data)
 			return buf.String(), funcMapGenerator.GetBodyDataHeader()
 		}
 	}
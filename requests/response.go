@@ -1,94 +1,410 @@
 package requests
 
 import (
-	"os"
+	"fmt"
+	"io"
+	"slices"
 	"time"
 
 	"github.com/aykhans/dodo/types"
 	"github.com/aykhans/dodo/utils"
 	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
 )
 
-type Response struct {
-	Response string
-	Time     time.Duration
+// Result holds the stats collected during a run together with the run metadata.
+// Percentiles are the latency percentiles reported by Print.
+// Series is only set if a time series interval was configured.
+// RateLimited reports whether the requests were sent on the schedule of a rate limiter.
+// Arrival and Dropped are only set for open model runs.
+// ReplaySpeed and Drift are only set for access log replays; Drift holds how far behind
+// its schedule each request was launched.
+// Steps are the names of the scenario steps in order, empty for runs without a scenario.
+// Endpoints are the names of the requests of the weighted mix, empty for runs without a mix.
+// Thresholds holds the outcome of the configured thresholds, evaluated after the run.
+type Result struct {
+	Stats       *Stats
+	Percentiles types.Percentiles
+	Series      *TimeSeries
+	StartTime   time.Time
+	EndTime     time.Time
+	TargetRate  uint
+	RateLimited bool
+	Arrival     string
+	Dropped     uint64
+	ReplaySpeed float64
+	Drift       *types.Histogram
+	Steps       []string
+	Endpoints   []string
+	Thresholds  []ThresholdResult
 }
 
-type Responses []Response
+// Elapsed returns the wall-clock duration of the run.
+func (result *Result) Elapsed() time.Duration {
+	return result.EndTime.Sub(result.StartTime)
+}
+
+// AchievedRate returns the number of completed requests per second over the whole run.
+func (result *Result) AchievedRate() float64 {
+	elapsed := result.Elapsed().Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(result.Stats.Count()) / elapsed
+}
 
-// Print prints the responses in a tabular format, including information such as
+// Print writes the stats to w in a tabular format, including information such as
 // response count, minimum time, maximum time, average time, and latency percentiles.
-func (responses Responses) Print() {
-	if len(responses) == 0 {
+// For scenarios, the responses of each step and the durations of the iterations follow.
+// If a target rate was set, the achieved rate is printed next to it, and for open model
+// runs the number of requests dropped because of the in-flight cap is printed as well.
+// For access log replays, the replay speed and the drift behind the original schedule are printed.
+// For rate limited runs, a second table compares the uncorrected latency percentiles
+// with the ones corrected for coordinated omission.
+// The throughput table shows the achieved rate and bandwidth of each status group, and
+// the time spent in each phase of the successful requests is broken down.
+// If checks were evaluated, the responses that passed and failed each check are counted.
+// If a time series was recorded, its sparklines are printed next, and
+// the outcome of the thresholds, if any were configured, is printed last.
+func (result *Result) Print(w io.Writer) {
+	stats := result.Stats
+	if stats.Count() == 0 {
 		return
 	}
 
-	mergedResponses := make(map[string]types.Durations)
+	t := table.NewWriter()
+	t.SetOutputMirror(w)
+	t.SetStyle(table.StyleLight)
+	t.SetColumnConfigs([]table.ColumnConfig{
+		{Number: 1, WidthMax: 40},
+	})
+
+	header := table.Row{"Response", "Count", "Min", "Max", "Average"}
+	for _, percentile := range result.Percentiles {
+		header = append(header, types.FormatPercentile(percentile))
+	}
+	t.AppendHeader(header)
 
-	totalDurations := make(types.Durations, len(responses))
-	var totalSum time.Duration
-	totalCount := len(responses)
+	var roundPrecision int64 = 4
 
-	for i, response := range responses {
-		totalSum += response.Time
-		totalDurations[i] = response.Time
+	categories := stats.Categories()
+	for _, category := range categories {
+		t.AppendRow(result.latencyRow(category, stats.Category(category).Latency, roundPrecision))
+		t.AppendSeparator()
+	}
+
+	if len(categories) > 1 {
+		t.AppendRow(result.latencyRow("Total", stats.Total().Latency, roundPrecision))
+	}
+
+	if result.TargetRate > 0 || result.Arrival != "" {
+		if len(categories) > 1 {
+			t.AppendSeparator()
+		}
+		if result.TargetRate > 0 {
+			t.AppendRow(table.Row{"Target Rate", fmt.Sprintf("%d/s", result.TargetRate)})
+		}
+		t.AppendRow(table.Row{"Achieved Rate", fmt.Sprintf("%.2f/s", result.AchievedRate())})
+		if result.Arrival != "" {
+			t.AppendRow(table.Row{"Dropped", result.Dropped})
+		}
+	}
+	if result.Drift != nil {
+		if len(categories) > 1 {
+			t.AppendSeparator()
+		}
+		t.AppendRow(table.Row{"Replay Speed", fmt.Sprintf("%gx", result.ReplaySpeed)})
+		t.AppendRow(table.Row{"Achieved Rate", fmt.Sprintf("%.2f/s", result.AchievedRate())})
+		t.AppendRow(result.latencyRow("Drift", result.Drift, roundPrecision))
+	}
+	t.Render()
+
+	if len(result.Steps) > 0 {
+		result.printRequests(w, "Step", result.Steps, true, roundPrecision)
+	}
+	if len(result.Endpoints) > 0 {
+		result.printRequests(w, "Endpoint", result.Endpoints, false, roundPrecision)
+	}
+	result.printThroughput(w)
+	if result.RateLimited {
+		result.printCorrectedLatency(w, roundPrecision)
+	}
+	result.printPhases(w, roundPrecision)
+	if len(stats.Checks()) > 0 {
+		result.printChecks(w)
+	}
+	if len(stats.Extractions()) > 0 {
+		result.printExtractions(w)
+	}
+	if result.Series != nil {
+		result.printSeries(w, roundPrecision)
+	}
+	if len(result.Thresholds) > 0 {
+		result.printThresholds(w, roundPrecision)
+	}
+}
 
-		mergedResponses[response.Response] = append(
-			mergedResponses[response.Response],
-			response.Time,
-		)
+// latencyRow returns the table row of a latency histogram with its count, min, max,
+// average and the configured percentiles.
+func (result *Result) latencyRow(name string, latency *types.Histogram, roundPrecision int64) table.Row {
+	row := table.Row{
+		name,
+		latency.Count(),
+		utils.DurationRoundBy(latency.Min(), roundPrecision),
+		utils.DurationRoundBy(latency.Max(), roundPrecision),
+		utils.DurationRoundBy(latency.Mean(), roundPrecision),
 	}
+	for _, percentile := range result.Percentiles {
+		row = append(row, utils.DurationRoundBy(latency.Percentile(percentile), roundPrecision))
+	}
+	return row
+}
 
+// printRequests prints the latency of the responses of each named request (the scenario
+// steps or the endpoints of the mix) by category, in the given order, under the given title.
+// If iterations is true, the latency of the completed iterations of the scenario follows.
+func (result *Result) printRequests(w io.Writer, title string, names []string, iterations bool, roundPrecision int64) {
 	t := table.NewWriter()
-	t.SetOutputMirror(os.Stdout)
+	t.SetOutputMirror(w)
 	t.SetStyle(table.StyleLight)
 	t.SetColumnConfigs([]table.ColumnConfig{
 		{Number: 1, WidthMax: 40},
+		{Number: 2, WidthMax: 40},
 	})
-	t.AppendHeader(table.Row{
-		"Response",
-		"Count",
-		"Min",
-		"Max",
-		"Average",
-		"P90",
-		"P95",
-		"P99",
+
+	header := table.Row{title, "Response", "Count", "Min", "Max", "Average"}
+	for _, percentile := range result.Percentiles {
+		header = append(header, types.FormatPercentile(percentile))
+	}
+	t.AppendHeader(header)
+
+	for _, name := range names {
+		requestStats := result.Stats.Step(name)
+		if requestStats == nil {
+			t.AppendRow(table.Row{name, "-", 0})
+			t.AppendSeparator()
+			continue
+		}
+		for i, category := range requestStats.Categories() {
+			rowName := ""
+			if i == 0 {
+				rowName = name
+			}
+			row := result.latencyRow(category, requestStats.Category(category).Latency, roundPrecision)
+			t.AppendRow(append(table.Row{rowName}, row...))
+		}
+		t.AppendSeparator()
+	}
+
+	if iterations {
+		row := result.latencyRow("-", result.Stats.Iterations(), roundPrecision)
+		t.AppendRow(append(table.Row{"Iteration"}, row...))
+	}
+	t.Render()
+}
+
+// printThroughput prints the elapsed time, and the achieved rate, bytes sent, bytes received
+// and average response size of each status group and of all responses.
+func (result *Result) printThroughput(w io.Writer) {
+	elapsed := result.Elapsed()
+	rate := func(count uint64) string {
+		if elapsed <= 0 {
+			return "-"
+		}
+		return fmt.Sprintf("%.2f/s", float64(count)/elapsed.Seconds())
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(w)
+	t.SetStyle(table.StyleLight)
+	t.SetTitle("Elapsed: %s", utils.DurationRoundBy(elapsed, 4))
+	t.SetColumnConfigs([]table.ColumnConfig{
+		{Number: 3, Align: text.AlignRight},
+		{Number: 4, Align: text.AlignRight},
+		{Number: 5, Align: text.AlignRight},
+		{Number: 6, Align: text.AlignRight},
 	})
+	t.AppendHeader(table.Row{"Status", "Count", "Rate", "Sent", "Received", "Avg Response Size"})
 
-	var roundPrecision int64 = 4
-	for key, durations := range mergedResponses {
-		durations.Sort()
-		durationsLen := len(durations)
-		durationsLenAsFloat := float64(durationsLen - 1)
+	throughputRow := func(name string, responseStats *ResponseStats) table.Row {
+		return table.Row{
+			name,
+			responseStats.Count(),
+			rate(responseStats.Count()),
+			utils.FormatBytes(responseStats.BytesSent),
+			utils.FormatBytes(responseStats.BytesReceived),
+			utils.FormatBytes(responseStats.AverageResponseSize()),
+		}
+	}
 
-		t.AppendRow(table.Row{
-			key,
-			durationsLen,
-			utils.DurationRoundBy(*durations.First(), roundPrecision),
-			utils.DurationRoundBy(*durations.Last(), roundPrecision),
-			utils.DurationRoundBy(durations.Avg(), roundPrecision),
-			utils.DurationRoundBy(durations[int(0.90*durationsLenAsFloat)], roundPrecision),
-			utils.DurationRoundBy(durations[int(0.95*durationsLenAsFloat)], roundPrecision),
-			utils.DurationRoundBy(durations[int(0.99*durationsLenAsFloat)], roundPrecision),
-		})
+	groupNames, groups := result.Stats.StatusGroups()
+	for _, group := range groupNames {
+		t.AppendRow(throughputRow(group, groups[group]))
+	}
+	if len(groupNames) > 1 {
 		t.AppendSeparator()
+		t.AppendRow(throughputRow("Total", result.Stats.Total()))
+	}
+	t.Render()
+}
+
+// printCorrectedLatency prints the median and the configured latency percentiles of all
+// responses, both measured from the actual send and from the scheduled send
+// (corrected for coordinated omission).
+func (result *Result) printCorrectedLatency(w io.Writer, roundPrecision int64) {
+	percentiles := result.Percentiles
+	if !slices.Contains(percentiles, 50) {
+		percentiles = append(types.Percentiles{50}, percentiles...)
+	}
+
+	header := table.Row{"Latency"}
+	for _, percentile := range percentiles {
+		header = append(header, types.FormatPercentile(percentile))
 	}
 
-	if len(mergedResponses) > 1 {
-		totalDurations.Sort()
-		allDurationsLenAsFloat := float64(len(totalDurations) - 1)
+	total := result.Stats.Total()
+
+	t := table.NewWriter()
+	t.SetOutputMirror(w)
+	t.SetStyle(table.StyleLight)
+	t.AppendHeader(header)
+	for _, row := range []struct {
+		name    string
+		latency *types.Histogram
+	}{
+		{"Uncorrected", total.Latency},
+		{"Corrected", total.CorrectedLatency},
+	} {
+		tableRow := table.Row{row.name}
+		for _, percentile := range percentiles {
+			tableRow = append(tableRow, utils.DurationRoundBy(row.latency.Percentile(percentile), roundPrecision))
+		}
+		t.AppendRow(tableRow)
+	}
+	t.Render()
+}
 
+// printPhases prints the min, average, P90 and P99 of the time spent in each phase of the
+// successful requests. The count of the DNS, Connect and TLS phases is the number of
+// connections that went through them, since reused connections skip those phases.
+func (result *Result) printPhases(w io.Writer, roundPrecision int64) {
+	phaseStats := result.Stats.Phases()
+	if phaseStats.Count() == 0 {
+		return
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(w)
+	t.SetStyle(table.StyleLight)
+	t.AppendHeader(table.Row{"Phase", "Count", "Min", "Average", "P90", "P99"})
+	for _, phase := range phaseStats.all() {
+		if phase.histogram.Count() == 0 {
+			continue
+		}
 		t.AppendRow(table.Row{
-			"Total",
-			totalCount,
-			utils.DurationRoundBy(totalDurations[0], roundPrecision),
-			utils.DurationRoundBy(totalDurations[len(totalDurations)-1], roundPrecision),
-			utils.DurationRoundBy(totalSum/time.Duration(totalCount), roundPrecision), // Average
-			utils.DurationRoundBy(totalDurations[int(0.90*allDurationsLenAsFloat)], roundPrecision),
-			utils.DurationRoundBy(totalDurations[int(0.95*allDurationsLenAsFloat)], roundPrecision),
-			utils.DurationRoundBy(totalDurations[int(0.99*allDurationsLenAsFloat)], roundPrecision),
+			phase.name,
+			phase.histogram.Count(),
+			utils.DurationRoundBy(phase.histogram.Min(), roundPrecision),
+			utils.DurationRoundBy(phase.histogram.Mean(), roundPrecision),
+			utils.DurationRoundBy(phase.histogram.Percentile(90), roundPrecision),
+			utils.DurationRoundBy(phase.histogram.Percentile(99), roundPrecision),
 		})
 	}
 	t.Render()
 }
+
+// printChecks prints the number of responses that passed and failed each check,
+// with the number of responses that failed any check in the title.
+func (result *Result) printChecks(w io.Writer) {
+	stats := result.Stats
+
+	t := table.NewWriter()
+	t.SetOutputMirror(w)
+	t.SetStyle(table.StyleLight)
+	t.SetTitle("Failed Checks: %d of %d responses", stats.FailedChecks(), stats.CheckedResponses())
+	t.SetColumnConfigs([]table.ColumnConfig{
+		{Number: 1, WidthMax: 60},
+	})
+	t.AppendHeader(table.Row{"Check", "Passed", "Failed"})
+	for _, name := range stats.Checks() {
+		checkStats := stats.Check(name)
+		failed := fmt.Sprint(checkStats.Failed)
+		if checkStats.Failed > 0 {
+			failed = text.FgRed.Sprint(failed)
+		}
+		t.AppendRow(table.Row{name, checkStats.Passed, failed})
+	}
+	t.Render()
+}
+
+// printExtractions prints the number of responses each variable was extracted from
+// and the number of responses it was missing from.
+func (result *Result) printExtractions(w io.Writer) {
+	stats := result.Stats
+
+	t := table.NewWriter()
+	t.SetOutputMirror(w)
+	t.SetStyle(table.StyleLight)
+	t.SetColumnConfigs([]table.ColumnConfig{
+		{Number: 1, WidthMax: 60},
+	})
+	t.AppendHeader(table.Row{"Extract", "Extracted", "Missed"})
+	for _, variable := range stats.Extractions() {
+		extractStats := stats.Extraction(variable)
+		missed := fmt.Sprint(extractStats.Missed)
+		if extractStats.Missed > 0 {
+			missed = text.FgYellow.Sprint(missed)
+		}
+		t.AppendRow(table.Row{variable, extractStats.Extracted, missed})
+	}
+	t.Render()
+}
+
+// sparklineWidth is the maximum number of characters of the time series sparklines.
+const sparklineWidth = 60
+
+// printSeries prints the requests per second, errors and highest configured latency
+// percentile of each interval of the time series as sparklines, with their min and max values.
+func (result *Result) printSeries(w io.Writer, roundPrecision int64) {
+	series := result.Series
+	intervals := series.Intervals()
+	if len(intervals) == 0 {
+		return
+	}
+
+	percentile := slices.Max(result.Percentiles)
+	var (
+		rates     = series.Rates()
+		errors    = make([]float64, len(intervals))
+		latencies = make([]float64, len(intervals))
+	)
+	for i, interval := range intervals {
+		errors[i] = float64(interval.Errors)
+		latencies[i] = float64(interval.Latency.Percentile(percentile))
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(w)
+	t.SetStyle(table.StyleLight)
+	t.SetTitle("Time Series: %d x %s", len(intervals), series.Interval())
+	t.AppendHeader(table.Row{"Series", "", "Min", "Max"})
+	t.AppendRow(table.Row{
+		"Requests/s",
+		utils.Sparkline(rates, sparklineWidth),
+		fmt.Sprintf("%.2f", slices.Min(rates)),
+		fmt.Sprintf("%.2f", slices.Max(rates)),
+	})
+	t.AppendRow(table.Row{
+		"Errors",
+		utils.Sparkline(errors, sparklineWidth),
+		slices.Min(errors),
+		slices.Max(errors),
+	})
+	t.AppendRow(table.Row{
+		types.FormatPercentile(percentile),
+		utils.Sparkline(latencies, sparklineWidth),
+		utils.DurationRoundBy(time.Duration(slices.Min(latencies)), roundPrecision),
+		utils.DurationRoundBy(time.Duration(slices.Max(latencies)), roundPrecision),
+	})
+	t.Render()
+}
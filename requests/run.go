@@ -8,81 +8,144 @@ import (
 
 	"github.com/aykhans/dodo/config"
 	"github.com/aykhans/dodo/types"
-	"github.com/aykhans/dodo/utils"
 	"github.com/valyala/fasthttp"
 )
 
 // Run executes the main logic for processing requests based on the provided configuration.
-// It initializes clients based on the request configuration and releases the dodos.
+// It initializes clients for every host of the requests and releases the dodos.
+// If a CSV file is configured, a row for every request is streamed to it during the run.
 // If the context is canceled and no responses are collected, it returns an interrupt error.
+// Otherwise the configured thresholds are evaluated on the result.
 //
 // Parameters:
 //   - ctx: The context for managing request lifecycle and cancellation.
 //   - requestConfig: The configuration for the request, including timeout, proxies, and other settings.
-func Run(ctx context.Context, requestConfig *config.RequestConfig) (Responses, error) {
-	if requestConfig.Duration > 0 {
+func Run(ctx context.Context, requestConfig *config.RequestConfig) (*Result, error) {
+	if duration := requestConfig.GetDuration(); duration > 0 {
 		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, requestConfig.Duration)
+		ctx, cancel = context.WithTimeout(ctx, duration)
 		defer cancel()
 	}
 
-	clients := getClients(
+	clients := getClientPool(
 		ctx,
 		requestConfig.Timeout,
 		requestConfig.Proxies,
 		requestConfig.GetMaxConns(fasthttp.DefaultMaxConnsPerHost),
-		requestConfig.URL,
+		requestConfig.GetRequestDefinitions(),
 		requestConfig.SkipVerify,
 	)
 	if clients == nil {
 		return nil, types.ErrInterrupt
 	}
+	factory := newScenarioFactory(requestConfig, clients)
 
-	responses := releaseDodos(ctx, requestConfig, clients)
-	if ctx.Err() != nil && len(responses) == 0 {
+	csv, err := newCSVWriter(requestConfig.CSVFile)
+	if err != nil {
+		return nil, err
+	}
+	outputs := statsOutputs{
+		series: newTimeSeries(requestConfig.Interval),
+		csv:    csv,
+	}
+
+	var result *Result
+	switch {
+	case len(requestConfig.ReplayOffsets) > 0:
+		result = releaseScheduledDodos(ctx, requestConfig, factory, outputs)
+	case requestConfig.Arrival != "":
+		result = releaseOpenDodos(ctx, requestConfig, factory, outputs)
+	case len(requestConfig.Stages) > 0:
+		result = releaseStagedDodos(ctx, requestConfig, factory, outputs)
+	default:
+		result = releaseDodos(ctx, requestConfig, factory, outputs)
+	}
+
+	if csv != nil {
+		if err := csv.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if ctx.Err() != nil && result.Stats.Count() == 0 {
 		return nil, types.ErrInterrupt
 	}
+	for _, step := range requestConfig.Scenario {
+		result.Steps = append(result.Steps, step.Name)
+	}
+	for _, request := range requestConfig.Mix {
+		result.Endpoints = append(result.Endpoints, request.Name)
+	}
+	result.Thresholds = result.evaluateThresholds(requestConfig.Thresholds)
 
-	return responses, nil
+	return result, nil
 }
 
-// releaseDodos sends requests concurrently using multiple dodos (goroutines) and returns the aggregated responses.
+// releaseDodos sends requests concurrently using multiple dodos (goroutines) and returns the aggregated result.
 //
 // The function performs the following steps:
 //  1. Initializes wait groups and other necessary variables.
 //  2. Starts a goroutine to stream progress updates.
 //  3. Distributes the total request count among the dodos.
-//  4. Starts a goroutine for each dodo to send requests concurrently.
+//  4. Starts a goroutine for each dodo to send requests concurrently,
+//     sharing a single rate limiter between them if a target rate is set.
 //  5. Waits for all dodos to complete their requests.
 //  6. Cancels the progress streaming context and waits for the progress goroutine to finish.
-//  7. Flattens and returns the aggregated responses.
+//  7. Merges the stats of all dodos and returns them with the run start and end times.
 func releaseDodos(
 	ctx context.Context,
 	requestConfig *config.RequestConfig,
-	clients []*fasthttp.HostClient,
-) Responses {
+	factory *scenarioFactory,
+	outputs statsOutputs,
+) *Result {
 	var (
 		wg                  sync.WaitGroup
 		streamWG            sync.WaitGroup
 		requestCountPerDodo uint
 		dodosCount          = requestConfig.GetValidDodosCountForRequests()
-		responses           = make([][]Response, dodosCount)
+		stats               = make([]*Stats, dodosCount)
 		increase            = make(chan int64, requestConfig.RequestCount)
+		limiter             *rateLimiter
 	)
 
 	wg.Add(int(dodosCount))
 	streamWG.Add(1)
 	streamCtx, streamCtxCancel := context.WithCancel(ctx)
 
-	go streamProgress(streamCtx, &streamWG, requestConfig.RequestCount, "Dodos Working🔥", increase)
+	// With a scenario, the request count and the progress count iterations.
+	message := "Dodos Working🔥"
+	if len(requestConfig.Scenario) > 0 {
+		message = "Dodos Working (iterations)🔥"
+	}
+	go streamProgress(
+		streamCtx,
+		&streamWG,
+		requestConfig.RequestCount,
+		message,
+		increase,
+		nil,
+		requestConfig.LogWriter(),
+	)
+
+	// The limiter is stopped together with the progress stream once all dodos are done.
+	if requestConfig.Rate > 0 {
+		limiter = newRateLimiter(streamCtx, float64(requestConfig.Rate), config.ArrivalConstant, requestConfig.Seed)
+	}
+
+	startTime := time.Now()
+
+	for i := range dodosCount {
+		stats[i] = newStats(int(requestConfig.HistogramSF), int64(i), outputs)
+	}
 
 	if requestConfig.RequestCount == 0 {
 		for i := range dodosCount {
 			go sendRequest(
 				ctx,
-				newRequest(*requestConfig, clients, int64(i)),
+				ctx,
+				factory.newScenario(int64(i)),
 				requestConfig.Timeout,
-				&responses[i],
+				limiter,
+				stats[i],
 				increase,
 				&wg,
 			)
@@ -98,10 +161,11 @@ func releaseDodos(
 
 			go sendRequestByCount(
 				ctx,
-				newRequest(*requestConfig, clients, int64(i)),
+				factory.newScenario(int64(i)),
 				requestConfig.Timeout,
+				limiter,
 				requestCountPerDodo,
-				&responses[i],
+				stats[i],
 				increase,
 				&wg,
 			)
@@ -109,21 +173,33 @@ func releaseDodos(
 	}
 
 	wg.Wait()
+	endTime := time.Now()
 	streamCtxCancel()
 	streamWG.Wait()
-	return utils.Flatten(responses)
+
+	return &Result{
+		Stats:       mergeStats(int(requestConfig.HistogramSF), stats),
+		Percentiles: requestConfig.Percentiles,
+		Series:      outputs.series,
+		StartTime:   startTime,
+		EndTime:     endTime,
+		TargetRate:  requestConfig.Rate,
+		RateLimited: limiter != nil,
+	}
 }
 
-// sendRequestByCount sends a specified number of HTTP requests concurrently with a given timeout.
-// It appends the responses to the provided responseData slice and sends the count of completed requests
-// to the increase channel. The function terminates early if the context is canceled or if a custom
-// interrupt error is encountered.
+// sendRequestByCount sends the requests of the scenario a specified number of times (iterations)
+// with a given timeout. It records the responses into the provided stats and sends the count of
+// completed iterations to the increase channel. The function terminates early if the context is
+// canceled, if a custom interrupt error is encountered or if the requests file was replayed to the end.
+// If a rate limiter is given, each iteration waits for its slot before being sent.
 func sendRequestByCount(
 	ctx context.Context,
-	request *Request,
+	scenario *Scenario,
 	timeout time.Duration,
+	limiter *rateLimiter,
 	requestCount uint,
-	responseData *[]Response,
+	stats *Stats,
 	increase chan<- int64,
 	wg *sync.WaitGroup,
 ) {
@@ -134,78 +210,153 @@ func sendRequestByCount(
 			return
 		}
 
-		func() {
-			startTime := time.Now()
-			response, err := request.Send(ctx, timeout)
-			completedTime := time.Since(startTime)
-			if response != nil {
-				defer fasthttp.ReleaseResponse(response)
-			}
-
-			if err != nil {
-				if err == types.ErrInterrupt {
-					return
-				}
-				*responseData = append(*responseData, Response{
-					Response: err.Error(),
-					Time:     completedTime,
-				})
-				increase <- 1
+		// The slot is waited for before taking the iteration, so that an interrupted wait
+		// doesn't use up a row of the data file or a request of the requests file.
+		var scheduledTime time.Time
+		if limiter != nil {
+			var err error
+			if scheduledTime, err = limiter.Wait(ctx); err != nil {
 				return
 			}
+		}
+
+		requests := scenario.Iteration()
+		if requests == nil {
+			return
+		}
 
-			*responseData = append(*responseData, Response{
-				Response: strconv.Itoa(response.StatusCode()),
-				Time:     completedTime,
-			})
-			increase <- 1
-		}()
+		sendIteration(ctx, requests, timeout, scheduledTime, stats, increase)
 	}
 }
 
-// sendRequest continuously sends HTTP requests until the context is canceled.
+// sendRequest continuously sends the requests of the scenario until the context is canceled
+// or the requests file was replayed to the end.
 // It records the response status code or error message along with the response time,
-// and signals each completed request through the increase channel.
+// and signals each completed iteration through the increase channel.
+// If a rate limiter is given, each iteration waits for its slot before being sent.
+//
+// The stop context, which must be the context or derived from it, also stops the dodo, but
+// unlike the context it lets the iteration in flight finish, so its responses are recorded.
 func sendRequest(
 	ctx context.Context,
-	request *Request,
+	stop context.Context,
+	scenario *Scenario,
 	timeout time.Duration,
-	responseData *[]Response,
+	limiter *rateLimiter,
+	stats *Stats,
 	increase chan<- int64,
 	wg *sync.WaitGroup,
 ) {
 	defer wg.Done()
 
 	for {
-		if ctx.Err() != nil {
+		if stop.Err() != nil {
 			return
 		}
 
-		func() {
-			startTime := time.Now()
-			response, err := request.Send(ctx, timeout)
-			completedTime := time.Since(startTime)
-			if response != nil {
-				defer fasthttp.ReleaseResponse(response)
-			}
-
-			if err != nil {
-				if err == types.ErrInterrupt {
-					return
-				}
-				*responseData = append(*responseData, Response{
-					Response: err.Error(),
-					Time:     completedTime,
-				})
-				increase <- 1
+		var scheduledTime time.Time
+		if limiter != nil {
+			var err error
+			if scheduledTime, err = limiter.Wait(stop); err != nil {
 				return
 			}
+		}
 
-			*responseData = append(*responseData, Response{
-				Response: strconv.Itoa(response.StatusCode()),
-				Time:     completedTime,
-			})
-			increase <- 1
-		}()
+		requests := scenario.Iteration()
+		if requests == nil {
+			return
+		}
+
+		sendIteration(ctx, requests, timeout, scheduledTime, stats, increase)
+	}
+}
+
+// sendIteration sends the requests of an iteration of the scenario in order, then signals the completed
+// iteration through the increase channel. If a request fails without a response, the rest
+// of the iteration is skipped, since the later steps of a scenario usually depend on it.
+// For scenarios with more than one step, the duration of every completed iteration is recorded.
+// Iterations interrupted by the context are not signaled.
+//
+// The scheduledTime is the time the iteration was intended to be sent by the rate limiter
+// and only applies to its first request; a zero scheduledTime means it was not scheduled.
+func sendIteration(
+	ctx context.Context,
+	requests []*Request,
+	timeout time.Duration,
+	scheduledTime time.Time,
+	stats *Stats,
+	increase chan<- int64,
+) {
+	startTime := time.Now()
+	completed := true
+	for i, request := range requests {
+		if i > 0 {
+			scheduledTime = time.Time{}
+		}
+		sent, err := sendSingleRequest(ctx, request, timeout, scheduledTime, stats)
+		if err == types.ErrInterrupt {
+			return
+		}
+		if !sent {
+			completed = false
+			break
+		}
+	}
+	if completed && len(requests) > 1 {
+		stats.RecordIteration(time.Since(startTime))
 	}
+
+	// The progress stream stops listening once the context is canceled,
+	// so an iteration that completes after that must not block on the channel.
+	select {
+	case increase <- 1:
+	case <-ctx.Done():
+	}
+}
+
+// sendSingleRequest sends one HTTP request and records the response status code or
+// error message along with the response time, the request trace and the outcome of the
+// checks of the response. It reports whether a response was received, and returns the error
+// of the request if it failed. Requests interrupted by the context are not recorded.
+//
+// The scheduledTime is the time the request was intended to be sent by the rate limiter.
+// Besides the response time measured from the actual send, the time measured from the
+// scheduled time is recorded as the latency corrected for coordinated omission.
+// A zero scheduledTime means the request was not scheduled, so both times are equal.
+func sendSingleRequest(
+	ctx context.Context,
+	request *Request,
+	timeout time.Duration,
+	scheduledTime time.Time,
+	stats *Stats,
+) (bool, error) {
+	startTime := time.Now()
+	if scheduledTime.IsZero() {
+		scheduledTime = startTime
+	}
+	response, trace, err := request.Send(ctx, timeout)
+	completedTime := time.Since(startTime)
+	correctedTime := time.Since(scheduledTime)
+	if response != nil {
+		defer fasthttp.ReleaseResponse(response)
+	}
+
+	if err != nil {
+		if err != types.ErrInterrupt {
+			stats.Record(request.name, err.Error(), completedTime, correctedTime, trace, nil, nil)
+		}
+		return false, err
+	}
+
+	checks, extractions := request.Check(response)
+	stats.Record(
+		request.name,
+		strconv.Itoa(response.StatusCode()),
+		completedTime,
+		correctedTime,
+		trace,
+		checks,
+		extractions,
+	)
+	return true, nil
 }
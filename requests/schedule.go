@@ -0,0 +1,125 @@
+package requests
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aykhans/dodo/config"
+	"github.com/aykhans/dodo/types"
+)
+
+// releaseScheduledDodos replays the requests of the access log on their original timing and
+// returns the aggregated result.
+//
+// Each request is launched at its offset from the first request of the log, divided by the
+// replay speed, regardless of the outstanding responses. Like in the open model, each launched
+// request borrows an idle dodo and returns it once the response is recorded, but if every dodo
+// is busy when a request is due, it waits for one instead of being dropped.
+// How far behind its schedule each request was launched is recorded as the drift of the replay,
+// and the latency corrected for coordinated omission is measured from the scheduled time.
+//
+// The launching stops when the context is canceled, when the requests were replayed to the end
+// or, if a request count is set, once that many requests have been launched.
+func releaseScheduledDodos(
+	ctx context.Context,
+	requestConfig *config.RequestConfig,
+	factory *scenarioFactory,
+	outputs statsOutputs,
+) *Result {
+	var (
+		wg         sync.WaitGroup
+		streamWG   sync.WaitGroup
+		offsets    = requestConfig.ReplayOffsets
+		speed      = requestConfig.AccessLogSpeed
+		dodosCount = requestConfig.GetValidDodosCountForRequests()
+		stats      = make([]*Stats, dodosCount)
+		scenarios  = make([]*Scenario, dodosCount)
+		idleDodos  = make(chan int, dodosCount)
+		increase   = make(chan int64, requestConfig.RequestCount)
+		messages   = make(chan string, 1)
+		drift      = types.NewHistogram(int(requestConfig.HistogramSF))
+		launched   uint
+	)
+
+	streamWG.Add(1)
+	streamCtx, streamCtxCancel := context.WithCancel(ctx)
+
+	go streamProgress(
+		streamCtx,
+		&streamWG,
+		requestConfig.RequestCount,
+		"Dodos Working🔥",
+		increase,
+		messages,
+		requestConfig.LogWriter(),
+	)
+
+	for i := range dodosCount {
+		scenarios[i] = factory.newScenario(int64(i))
+		stats[i] = newStats(int(requestConfig.HistogramSF), int64(i), outputs)
+		idleDodos <- int(i)
+	}
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	startTime := time.Now()
+
+launching:
+	for launched < uint(len(offsets)) &&
+		(requestConfig.RequestCount == 0 || launched < requestConfig.RequestCount) {
+		scheduledTime := startTime.Add(time.Duration(float64(offsets[launched]) / speed))
+		timer.Reset(time.Until(scheduledTime))
+		select {
+		case <-ctx.Done():
+			break launching
+		case <-timer.C:
+		}
+
+		var i int
+		select {
+		case <-ctx.Done():
+			break launching
+		case i = <-idleDodos:
+		}
+
+		// The requests are replayed in sequential order from this goroutine only,
+		// so the request of the iteration is the one the offset was taken from.
+		requests := scenarios[i].Iteration()
+		if requests == nil {
+			break launching
+		}
+		lag := time.Since(scheduledTime)
+		drift.Record(lag)
+		launched++
+
+		select {
+		case messages <- fmt.Sprintf("Replaying (%s behind)🔥", lag.Round(time.Millisecond)):
+		default:
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sendIteration(ctx, requests, requestConfig.Timeout, scheduledTime, stats[i], increase)
+			idleDodos <- i
+		}()
+	}
+
+	wg.Wait()
+	endTime := time.Now()
+	streamCtxCancel()
+	streamWG.Wait()
+
+	return &Result{
+		Stats:       mergeStats(int(requestConfig.HistogramSF), stats),
+		Percentiles: requestConfig.Percentiles,
+		Series:      outputs.series,
+		StartTime:   startTime,
+		EndTime:     endTime,
+		RateLimited: true,
+		ReplaySpeed: speed,
+		Drift:       drift,
+	}
+}
@@ -0,0 +1,83 @@
+package requests
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aykhans/dodo/types"
+)
+
+// seriesSignificantFigures is the precision of the per-interval latency histograms.
+// It is lower than the precision of the overall histograms to keep long series small.
+const seriesSignificantFigures = 2
+
+// Interval holds the requests completed during one interval of a TimeSeries.
+// Errors counts the requests that failed or got a 4xx or 5xx response.
+type Interval struct {
+	Requests uint64
+	Errors   uint64
+	Latency  *types.Histogram
+}
+
+// TimeSeries buckets the completed requests into fixed intervals measured from its creation,
+// so the course of the run can be reported after it is over.
+// Unlike Stats, a single TimeSeries is shared by all dodos; it is safe for concurrent use.
+type TimeSeries struct {
+	mu        sync.Mutex
+	interval  time.Duration
+	startTime time.Time
+	intervals []Interval
+}
+
+// newTimeSeries creates a TimeSeries with the given interval that starts now.
+// It returns nil if the interval is 0, which disables the time series.
+func newTimeSeries(interval time.Duration) *TimeSeries {
+	if interval <= 0 {
+		return nil
+	}
+	return &TimeSeries{
+		interval:  interval,
+		startTime: time.Now(),
+	}
+}
+
+// record adds a request that completed now to the current interval.
+func (ts *TimeSeries) record(latency time.Duration, failed bool) {
+	index := int(time.Since(ts.startTime) / ts.interval)
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	for len(ts.intervals) <= index {
+		ts.intervals = append(ts.intervals, Interval{Latency: types.NewHistogram(seriesSignificantFigures)})
+	}
+	ts.intervals[index].Requests++
+	if failed {
+		ts.intervals[index].Errors++
+	}
+	ts.intervals[index].Latency.Record(latency)
+}
+
+// Interval returns the length of the intervals.
+func (ts *TimeSeries) Interval() time.Duration {
+	return ts.interval
+}
+
+// Intervals returns the recorded intervals in order, the first one starting when the series was created.
+// Intervals without completed requests are included with zero counts.
+func (ts *TimeSeries) Intervals() []Interval {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return ts.intervals
+}
+
+// Rates returns the requests per second of each interval.
+// The last interval may be cut short by the end of the run, so its rate may be lower.
+func (ts *TimeSeries) Rates() []float64 {
+	intervals := ts.Intervals()
+	rates := make([]float64, len(intervals))
+	for i, interval := range intervals {
+		rates[i] = float64(interval.Requests) / ts.interval.Seconds()
+	}
+	return rates
+}
@@ -0,0 +1,218 @@
+package requests
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/aykhans/dodo/config"
+	"github.com/aykhans/dodo/types"
+)
+
+// stageTickInterval is how often the stage controller recalculates the load targets.
+const stageTickInterval = 100 * time.Millisecond
+
+// stageTarget returns the index of the stage that is active after the given elapsed time
+// and the target of that moment, interpolated linearly between the previous stage's
+// target and the active stage's target. The first stage ramps up from 0.
+// Once all stages are over, the last stage's index and target are returned.
+func stageTarget(stages types.Stages, elapsed time.Duration) (int, float64) {
+	from := 0.0
+	for i, stage := range stages {
+		to := float64(stage.Target())
+		if elapsed < stage.Duration.Duration {
+			progress := float64(elapsed) / float64(stage.Duration.Duration)
+			return i, from + (to-from)*progress
+		}
+		elapsed -= stage.Duration.Duration
+		from = to
+	}
+	return len(stages) - 1, from
+}
+
+// rateStageMessage returns the progress bar message for a rate targeted stage.
+func rateStageMessage(stageIndex, stagesCount int, rate float64) string {
+	return fmt.Sprintf("Stage %d/%d (%.0f/s)🔥", stageIndex+1, stagesCount, rate)
+}
+
+// controlStages calls apply with the active stage index and its current target every
+// stageTickInterval until the context is canceled.
+// The message returned by apply is sent to the messages channel whenever it changes;
+// messages are dropped if the channel is not ready to receive them.
+func controlStages(
+	ctx context.Context,
+	stages types.Stages,
+	messages chan<- string,
+	apply func(stageIndex int, target float64) string,
+) {
+	ticker := time.NewTicker(stageTickInterval)
+	defer ticker.Stop()
+
+	var (
+		startTime   = time.Now()
+		lastMessage string
+	)
+
+	for ctx.Err() == nil {
+		stageIndex, target := stageTarget(stages, time.Since(startTime))
+
+		if message := apply(stageIndex, target); message != lastMessage {
+			select {
+			case messages <- message:
+				lastMessage = message
+			default:
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-ticker.C:
+		}
+	}
+}
+
+// releaseStagedDodos runs the load profile described by the request config stages and
+// returns the aggregated result.
+//
+// Every stageTickInterval the controller calculates the current stage target and:
+//   - for dodos targeted stages, spawns new dodos or retires the most recently spawned ones
+//     until the number of running dodos matches the target; a retired dodo finishes the
+//     iteration in flight, so its responses are still recorded;
+//   - a spawned dodo takes the lowest slot whose dodo has exited as its index, so the indexes
+//     (and the rows of the unique data mode) stay below the highest dodos target; if all
+//     slots are taken by retired dodos that are still finishing, it is spawned on a later tick;
+//   - for rate targeted stages, updates the rate of the limiter shared by the dodos.
+//
+// The progress bar message shows the active stage and its current target.
+func releaseStagedDodos(
+	ctx context.Context,
+	requestConfig *config.RequestConfig,
+	factory *scenarioFactory,
+	outputs statsOutputs,
+) *Result {
+	var (
+		wg          sync.WaitGroup
+		streamWG    sync.WaitGroup
+		stages      = requestConfig.Stages
+		stats       []*Stats             // stats of the dodo slots, indexed by the dodo index
+		dodoCancels []context.CancelFunc // cancel functions of the running dodos, oldest first
+		slotsMu     sync.Mutex
+		busySlots   []bool // whether the dodo of each slot hasn't exited yet
+		maxSlots    = int(stages.MaxDodos())
+		increase    = make(chan int64)
+		messages    = make(chan string, 1)
+		limiter     *rateLimiter
+	)
+
+	streamWG.Add(1)
+	streamCtx, streamCtxCancel := context.WithCancel(ctx)
+
+	go streamProgress(
+		streamCtx,
+		&streamWG,
+		0,
+		"Dodos Working🔥",
+		increase,
+		messages,
+		requestConfig.LogWriter(),
+	)
+
+	if stages.TargetsRate() {
+		limiter = newRateLimiter(streamCtx, 0, config.ArrivalConstant, requestConfig.Seed)
+	} else if requestConfig.Rate > 0 {
+		limiter = newRateLimiter(streamCtx, float64(requestConfig.Rate), config.ArrivalConstant, requestConfig.Seed)
+	}
+
+	if stages.TargetsRate() {
+		maxSlots = int(requestConfig.DodosCount)
+	}
+
+	// spawnDodo starts a dodo in the lowest free slot and reports false if there is none.
+	// The stats of a slot are reused by its next dodo, since the previous one has exited.
+	spawnDodo := func() bool {
+		slotsMu.Lock()
+		slot := slices.Index(busySlots, false)
+		if slot == -1 {
+			if len(busySlots) >= maxSlots {
+				slotsMu.Unlock()
+				return false
+			}
+			slot = len(busySlots)
+			busySlots = append(busySlots, true)
+			stats = append(stats, newStats(int(requestConfig.HistogramSF), int64(slot), outputs))
+		}
+		busySlots[slot] = true
+		dodoStats := stats[slot]
+		slotsMu.Unlock()
+
+		stopCtx, stopCtxCancel := context.WithCancel(ctx)
+		dodoCancels = append(dodoCancels, stopCtxCancel)
+
+		wg.Add(1)
+		go func() {
+			defer func() {
+				slotsMu.Lock()
+				busySlots[slot] = false
+				slotsMu.Unlock()
+			}()
+			sendRequest(
+				ctx,
+				stopCtx,
+				factory.newScenario(int64(slot)),
+				requestConfig.Timeout,
+				limiter,
+				dodoStats,
+				increase,
+				&wg,
+			)
+		}()
+		return true
+	}
+
+	if stages.TargetsRate() {
+		for range requestConfig.DodosCount {
+			spawnDodo()
+		}
+	}
+
+	startTime := time.Now()
+	controlStages(ctx, stages, messages, func(stageIndex int, target float64) string {
+		if stages.TargetsRate() {
+			limiter.SetRate(target)
+			return rateStageMessage(stageIndex, len(stages), target)
+		}
+
+		targetDodos := int(math.Round(target))
+		for len(dodoCancels) < targetDodos {
+			if !spawnDodo() {
+				break
+			}
+		}
+		for len(dodoCancels) > targetDodos {
+			dodoCancels[len(dodoCancels)-1]()
+			dodoCancels = dodoCancels[:len(dodoCancels)-1]
+		}
+		return fmt.Sprintf("Stage %d/%d (%d dodos)🔥", stageIndex+1, len(stages), len(dodoCancels))
+	})
+
+	for _, cancel := range dodoCancels {
+		cancel()
+	}
+	wg.Wait()
+	endTime := time.Now()
+	streamCtxCancel()
+	streamWG.Wait()
+
+	return &Result{
+		Stats:       mergeStats(int(requestConfig.HistogramSF), stats),
+		Percentiles: requestConfig.Percentiles,
+		Series:      outputs.series,
+		StartTime:   startTime,
+		EndTime:     endTime,
+		TargetRate:  requestConfig.Rate,
+		RateLimited: limiter != nil,
+	}
+}
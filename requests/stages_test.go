@@ -0,0 +1,38 @@
+package requests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aykhans/dodo/types"
+	"github.com/aykhans/dodo/utils"
+)
+
+func TestStageTarget(t *testing.T) {
+	stage := func(duration time.Duration, rate uint) types.Stage {
+		return types.Stage{Duration: types.Duration{Duration: duration}, Rate: utils.ToPtr(rate)}
+	}
+	// Ramp up to 100/s, hold it, then ramp down to 0.
+	stages := types.Stages{stage(10*time.Second, 100), stage(10*time.Second, 100), stage(5*time.Second, 0)}
+
+	tests := []struct {
+		elapsed    time.Duration
+		wantIndex  int
+		wantTarget float64
+	}{
+		{elapsed: 0, wantIndex: 0, wantTarget: 0},
+		{elapsed: 2500 * time.Millisecond, wantIndex: 0, wantTarget: 25},
+		{elapsed: 10 * time.Second, wantIndex: 1, wantTarget: 100},
+		{elapsed: 15 * time.Second, wantIndex: 1, wantTarget: 100},
+		{elapsed: 21 * time.Second, wantIndex: 2, wantTarget: 80},
+		{elapsed: 25 * time.Second, wantIndex: 2, wantTarget: 0},
+		{elapsed: time.Minute, wantIndex: 2, wantTarget: 0},
+	}
+
+	for _, test := range tests {
+		index, target := stageTarget(stages, test.elapsed)
+		if index != test.wantIndex || target != test.wantTarget {
+			t.Errorf("stageTarget(%v) = (%d, %v), want (%d, %v)", test.elapsed, index, target, test.wantIndex, test.wantTarget)
+		}
+	}
+}
@@ -0,0 +1,426 @@
+package requests
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/aykhans/dodo/types"
+)
+
+// ResponseStats aggregates the responses of a single category (status code or error message).
+// Latency is measured from the moment the request was actually sent, while CorrectedLatency
+// is measured from the moment the rate limiter scheduled it, so it includes the time the
+// request waited for a free dodo (coordinated omission correction).
+// BytesSent and BytesReceived are the sizes of the requests and responses, headers included.
+type ResponseStats struct {
+	Latency          *types.Histogram
+	CorrectedLatency *types.Histogram
+	BytesSent        uint64
+	BytesReceived    uint64
+}
+
+func newResponseStats(significantFigures int) *ResponseStats {
+	return &ResponseStats{
+		Latency:          types.NewHistogram(significantFigures),
+		CorrectedLatency: types.NewHistogram(significantFigures),
+	}
+}
+
+// Count returns the number of responses in the category.
+func (rs *ResponseStats) Count() uint64 {
+	return rs.Latency.Count()
+}
+
+// AverageResponseSize returns the average number of bytes received per response.
+func (rs *ResponseStats) AverageResponseSize() uint64 {
+	if rs.Count() == 0 {
+		return 0
+	}
+	return rs.BytesReceived / rs.Count()
+}
+
+func (rs *ResponseStats) merge(other *ResponseStats) {
+	rs.Latency.Merge(other.Latency)
+	rs.CorrectedLatency.Merge(other.CorrectedLatency)
+	rs.BytesSent += other.BytesSent
+	rs.BytesReceived += other.BytesReceived
+}
+
+// PhaseStats aggregates the time spent in each phase of the requests.
+// Phases that didn't happen for a request (e.g. DNS on a reused connection) are not recorded,
+// so the count of the dial phases is the number of connections opened.
+type PhaseStats struct {
+	DNS     *types.Histogram
+	Connect *types.Histogram
+	TLS     *types.Histogram
+	TTFB    *types.Histogram
+	Body    *types.Histogram
+}
+
+func newPhaseStats(significantFigures int) *PhaseStats {
+	return &PhaseStats{
+		DNS:     types.NewHistogram(significantFigures),
+		Connect: types.NewHistogram(significantFigures),
+		TLS:     types.NewHistogram(significantFigures),
+		TTFB:    types.NewHistogram(significantFigures),
+		Body:    types.NewHistogram(significantFigures),
+	}
+}
+
+type phaseHistogram struct {
+	name      string
+	histogram *types.Histogram
+}
+
+// all returns the phase histograms with their names, in the order the phases happen.
+func (ps *PhaseStats) all() []phaseHistogram {
+	return []phaseHistogram{
+		{"DNS", ps.DNS},
+		{"Connect", ps.Connect},
+		{"TLS", ps.TLS},
+		{"TTFB", ps.TTFB},
+		{"Body", ps.Body},
+	}
+}
+
+// Count returns the number of recorded phases of all kinds.
+func (ps *PhaseStats) Count() uint64 {
+	count := uint64(0)
+	for _, phase := range ps.all() {
+		count += phase.histogram.Count()
+	}
+	return count
+}
+
+func (ps *PhaseStats) record(phases Phases) {
+	histograms := ps.all()
+	for i, duration := range []time.Duration{phases.DNS, phases.Connect, phases.TLS, phases.TTFB, phases.Body} {
+		if duration > 0 {
+			histograms[i].histogram.Record(duration)
+		}
+	}
+}
+
+func (ps *PhaseStats) merge(other *PhaseStats) {
+	otherPhases := other.all()
+	for i, phase := range ps.all() {
+		phase.histogram.Merge(otherPhases[i].histogram)
+	}
+}
+
+// statsOutputs are the outputs shared by all dodos that every response is recorded to
+// besides the stats of its dodo. Nil outputs are disabled.
+type statsOutputs struct {
+	series *TimeSeries
+	csv    *csvWriter
+}
+
+// Stats aggregates responses by category into histograms, so its memory usage doesn't
+// grow with the number of requests. The responses of scenario steps are also aggregated
+// into a nested Stats per step, and the durations of the scenario iterations into a histogram.
+// It isn't thread-safe; each dodo records into its own Stats, which are merged after the run.
+// The only shared parts are the outputs, which are safe for concurrent use.
+type Stats struct {
+	significantFigures int
+	dodoID             int64
+	categories         map[string]*ResponseStats
+	phases             *PhaseStats
+	checks             map[string]*CheckStats
+	checkedResponses   uint64
+	failedChecks       uint64
+	extractions        map[string]*ExtractStats
+	steps              map[string]*Stats
+	iterations         *types.Histogram
+	outputs            statsOutputs
+}
+
+// newStats creates an empty Stats for the dodo with the given id, whose histograms keep
+// the given number of significant figures. The responses are also recorded to the outputs.
+func newStats(significantFigures int, dodoID int64, outputs statsOutputs) *Stats {
+	return &Stats{
+		significantFigures: significantFigures,
+		dodoID:             dodoID,
+		categories:         make(map[string]*ResponseStats),
+		phases:             newPhaseStats(significantFigures),
+		checks:             make(map[string]*CheckStats),
+		extractions:        make(map[string]*ExtractStats),
+		steps:              make(map[string]*Stats),
+		iterations:         types.NewHistogram(significantFigures),
+		outputs:            outputs,
+	}
+}
+
+// Record adds a response of the given category with its latency, corrected latency, trace
+// and the outcome of its checks and extractions. Failed checks don't change the category of
+// the response; they are counted separately. Missed extractions are counted per variable,
+// apart from the checks.
+// If the response belongs to a scenario step, it is also recorded to the stats of the step.
+func (s *Stats) Record(
+	step string,
+	category string,
+	latency, correctedLatency time.Duration,
+	trace Trace,
+	checks []CheckResult,
+	extractions []ExtractResult,
+) {
+	responseStats, ok := s.categories[category]
+	if !ok {
+		responseStats = newResponseStats(s.significantFigures)
+		s.categories[category] = responseStats
+	}
+
+	if step != "" {
+		stepStats, ok := s.steps[step]
+		if !ok {
+			stepStats = newStats(s.significantFigures, s.dodoID, statsOutputs{})
+			s.steps[step] = stepStats
+		}
+		stepStats.Record("", category, latency, correctedLatency, trace, checks, extractions)
+	}
+
+	responseStats.Latency.Record(latency)
+	responseStats.CorrectedLatency.Record(correctedLatency)
+	responseStats.BytesSent += trace.BytesSent
+	responseStats.BytesReceived += trace.BytesReceived
+	s.phases.record(trace.Phases)
+
+	var failedChecks []string
+	for _, check := range checks {
+		checkStats, ok := s.checks[check.Name]
+		if !ok {
+			checkStats = &CheckStats{}
+			s.checks[check.Name] = checkStats
+		}
+		if check.Passed {
+			checkStats.Passed++
+		} else {
+			checkStats.Failed++
+			failedChecks = append(failedChecks, check.Name)
+		}
+	}
+	if len(checks) > 0 {
+		s.checkedResponses++
+	}
+	if len(failedChecks) > 0 {
+		s.failedChecks++
+	}
+
+	for _, extraction := range extractions {
+		extractStats, ok := s.extractions[extraction.Var]
+		if !ok {
+			extractStats = &ExtractStats{}
+			s.extractions[extraction.Var] = extractStats
+		}
+		if extraction.Extracted {
+			extractStats.Extracted++
+		} else {
+			extractStats.Missed++
+		}
+	}
+
+	if s.outputs.series != nil {
+		s.outputs.series.record(latency, isErrorCategory(category))
+	}
+	if s.outputs.csv != nil {
+		s.outputs.csv.record(s.dodoID, step, category, latency, correctedLatency, trace, failedChecks)
+	}
+}
+
+// RecordIteration adds the duration of a completed scenario iteration.
+func (s *Stats) RecordIteration(duration time.Duration) {
+	s.iterations.Record(duration)
+}
+
+// Merge adds all the responses recorded in the other Stats to this one.
+func (s *Stats) Merge(other *Stats) {
+	s.phases.merge(other.phases)
+	s.iterations.Merge(other.iterations)
+
+	for step, otherStepStats := range other.steps {
+		stepStats, ok := s.steps[step]
+		if !ok {
+			stepStats = newStats(s.significantFigures, s.dodoID, statsOutputs{})
+			s.steps[step] = stepStats
+		}
+		stepStats.Merge(otherStepStats)
+	}
+
+	for name, otherCheckStats := range other.checks {
+		checkStats, ok := s.checks[name]
+		if !ok {
+			checkStats = &CheckStats{}
+			s.checks[name] = checkStats
+		}
+		checkStats.Passed += otherCheckStats.Passed
+		checkStats.Failed += otherCheckStats.Failed
+	}
+	s.checkedResponses += other.checkedResponses
+	s.failedChecks += other.failedChecks
+
+	for variable, otherExtractStats := range other.extractions {
+		extractStats, ok := s.extractions[variable]
+		if !ok {
+			extractStats = &ExtractStats{}
+			s.extractions[variable] = extractStats
+		}
+		extractStats.Extracted += otherExtractStats.Extracted
+		extractStats.Missed += otherExtractStats.Missed
+	}
+
+	for category, otherResponseStats := range other.categories {
+		responseStats, ok := s.categories[category]
+		if !ok {
+			responseStats = newResponseStats(s.significantFigures)
+			s.categories[category] = responseStats
+		}
+		responseStats.merge(otherResponseStats)
+	}
+}
+
+// Step returns the stats of the responses of the scenario step with the given name,
+// or nil if none were recorded.
+func (s *Stats) Step(name string) *Stats {
+	return s.steps[name]
+}
+
+// Iterations returns the durations of the completed scenario iterations.
+// Nothing is recorded for runs without a scenario.
+func (s *Stats) Iterations() *types.Histogram {
+	return s.iterations
+}
+
+// Phases returns the aggregated phases of the requests.
+func (s *Stats) Phases() *PhaseStats {
+	return s.phases
+}
+
+// Checks returns the names of the evaluated checks in ascending order.
+func (s *Stats) Checks() []string {
+	names := make([]string, 0, len(s.checks))
+	for name := range s.checks {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Check returns the outcomes of the check with the given name, or nil if it was never evaluated.
+func (s *Stats) Check(name string) *CheckStats {
+	return s.checks[name]
+}
+
+// CheckedResponses returns the number of responses the checks were evaluated on.
+// Failed requests without a response are not checked, so they aren't included.
+func (s *Stats) CheckedResponses() uint64 {
+	return s.checkedResponses
+}
+
+// FailedChecks returns the number of responses that failed at least one check.
+func (s *Stats) FailedChecks() uint64 {
+	return s.failedChecks
+}
+
+// Extractions returns the variables of the evaluated extractors in ascending order.
+func (s *Stats) Extractions() []string {
+	variables := make([]string, 0, len(s.extractions))
+	for variable := range s.extractions {
+		variables = append(variables, variable)
+	}
+	slices.Sort(variables)
+	return variables
+}
+
+// Extraction returns the outcomes of the extractors of the given variable, or nil if none were evaluated.
+func (s *Stats) Extraction(variable string) *ExtractStats {
+	return s.extractions[variable]
+}
+
+// Categories returns the recorded categories in ascending order.
+func (s *Stats) Categories() []string {
+	categories := make([]string, 0, len(s.categories))
+	for category := range s.categories {
+		categories = append(categories, category)
+	}
+	slices.Sort(categories)
+	return categories
+}
+
+// Category returns the aggregated responses of the given category, or nil if none were recorded.
+func (s *Stats) Category(category string) *ResponseStats {
+	return s.categories[category]
+}
+
+// StatusGroups returns the aggregated responses grouped by status class (e.g. "2xx", "5xx"),
+// with all errors in the "Errors" group, in ascending order.
+func (s *Stats) StatusGroups() ([]string, map[string]*ResponseStats) {
+	groups := make(map[string]*ResponseStats)
+	for category, responseStats := range s.categories {
+		group := errorsGroup
+		if statusCode, err := strconv.Atoi(category); err == nil {
+			group = fmt.Sprintf("%dxx", statusCode/100)
+		}
+
+		groupStats, ok := groups[group]
+		if !ok {
+			groupStats = newResponseStats(s.significantFigures)
+			groups[group] = groupStats
+		}
+		groupStats.merge(responseStats)
+	}
+
+	names := make([]string, 0, len(groups))
+	for group := range groups {
+		names = append(names, group)
+	}
+	slices.Sort(names)
+	return names, groups
+}
+
+// errorsGroup is the status group of the requests that failed without a response.
+const errorsGroup = "Errors"
+
+// isErrorCategory reports whether the category is an error message or a 4xx or 5xx status code.
+func isErrorCategory(category string) bool {
+	statusCode, err := strconv.Atoi(category)
+	return err != nil || statusCode >= 400
+}
+
+// Total returns the aggregated responses of all categories.
+func (s *Stats) Total() *ResponseStats {
+	total := newResponseStats(s.significantFigures)
+	for _, responseStats := range s.categories {
+		total.merge(responseStats)
+	}
+	return total
+}
+
+// Count returns the number of recorded responses.
+func (s *Stats) Count() uint64 {
+	count := uint64(0)
+	for _, responseStats := range s.categories {
+		count += responseStats.Count()
+	}
+	return count
+}
+
+// ErrorCount returns the number of requests that failed or got a 4xx or 5xx response.
+func (s *Stats) ErrorCount() uint64 {
+	count := uint64(0)
+	for category, responseStats := range s.categories {
+		if isErrorCategory(category) {
+			count += responseStats.Count()
+		}
+	}
+	return count
+}
+
+// mergeStats merges the stats of all dodos into a single Stats.
+func mergeStats(significantFigures int, dodoStats []*Stats) *Stats {
+	merged := newStats(significantFigures, 0, statsOutputs{})
+	for _, stats := range dodoStats {
+		merged.Merge(stats)
+	}
+	return merged
+}
@@ -0,0 +1,134 @@
+package requests
+
+import (
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aykhans/dodo/types"
+	"github.com/aykhans/dodo/utils"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+)
+
+// ThresholdResult is the outcome of a threshold evaluated on the result of a run.
+type ThresholdResult struct {
+	Threshold types.Threshold
+	Actual    float64
+	Passed    bool
+}
+
+// evaluateThresholds evaluates the thresholds on the stats of the result.
+// A metric limited to a status without any responses is evaluated as 0.
+func (result *Result) evaluateThresholds(thresholds types.Thresholds) []ThresholdResult {
+	results := make([]ThresholdResult, 0, len(thresholds))
+	for _, threshold := range thresholds {
+		actual := result.thresholdMetric(threshold)
+		results = append(results, ThresholdResult{
+			Threshold: threshold,
+			Actual:    actual,
+			Passed:    threshold.Passes(actual),
+		})
+	}
+	return results
+}
+
+// thresholdMetric returns the actual value of the metric of the threshold,
+// in the same unit as the threshold value.
+func (result *Result) thresholdMetric(threshold types.Threshold) float64 {
+	stats := result.Stats
+	totalCount := stats.Count()
+
+	switch threshold.Metric {
+	case types.ThresholdErrorRate:
+		if totalCount == 0 {
+			return 0
+		}
+		return float64(stats.ErrorCount()) / float64(totalCount)
+	case types.ThresholdErrors:
+		return float64(stats.ErrorCount())
+	case types.ThresholdCheckRate:
+		if stats.CheckedResponses() == 0 {
+			return 0
+		}
+		return float64(stats.FailedChecks()) / float64(stats.CheckedResponses())
+	case types.ThresholdChecks:
+		return float64(stats.FailedChecks())
+	}
+
+	responseStats := result.scopeStats(threshold.Scope)
+	switch threshold.Metric {
+	case types.ThresholdPercentile:
+		return float64(responseStats.Latency.Percentile(threshold.Percentile))
+	case types.ThresholdAverage:
+		return float64(responseStats.Latency.Mean())
+	case types.ThresholdMin:
+		return float64(responseStats.Latency.Min())
+	case types.ThresholdMax:
+		return float64(responseStats.Latency.Max())
+	case types.ThresholdRPS:
+		return perSecond(responseStats.Count(), result.Elapsed())
+	case types.ThresholdRatio:
+		if totalCount == 0 {
+			return 0
+		}
+		return float64(responseStats.Count()) / float64(totalCount)
+	}
+	return float64(responseStats.Count())
+}
+
+// scopeStats returns the responses of the status code or status group of the scope,
+// or of all responses if the scope is empty.
+func (result *Result) scopeStats(scope string) *ResponseStats {
+	stats := result.Stats
+	if scope == "" {
+		return stats.Total()
+	}
+	if responseStats := stats.Category(scope); responseStats != nil {
+		return responseStats
+	}
+
+	groupNames, groups := stats.StatusGroups()
+	for _, group := range groupNames {
+		if strings.EqualFold(group, scope) {
+			return groups[group]
+		}
+	}
+	return newResponseStats(stats.significantFigures)
+}
+
+// ThresholdsPassed reports whether all thresholds of the run passed.
+// It is true if no thresholds were configured.
+func (result *Result) ThresholdsPassed() bool {
+	for _, thresholdResult := range result.Thresholds {
+		if !thresholdResult.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// printThresholds prints the actual value of the metric of each threshold and whether it passed.
+func (result *Result) printThresholds(w io.Writer, roundPrecision int64) {
+	t := table.NewWriter()
+	t.SetOutputMirror(w)
+	t.SetStyle(table.StyleLight)
+	t.SetColumnConfigs([]table.ColumnConfig{
+		{Number: 2, Align: text.AlignRight},
+	})
+	t.AppendHeader(table.Row{"Threshold", "Actual", "Result"})
+	for _, thresholdResult := range result.Thresholds {
+		threshold := thresholdResult.Threshold
+		actual := threshold.FormatValue(thresholdResult.Actual)
+		if threshold.IsLatency() {
+			actual = utils.DurationRoundBy(time.Duration(thresholdResult.Actual), roundPrecision).String()
+		}
+
+		status := text.FgGreen.Sprint("PASS")
+		if !thresholdResult.Passed {
+			status = text.FgRed.Sprint("FAIL")
+		}
+		t.AppendRow(table.Row{threshold.String(), actual, status})
+	}
+	t.Render()
+}
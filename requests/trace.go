@@ -0,0 +1,205 @@
+package requests
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Phases holds the time spent in each phase of a request.
+// DNS, Connect and TLS are only set for the request that opened a new connection;
+// they are zero when an idle connection was reused or when the phase didn't happen
+// (e.g. DNS for IP addresses, TLS for plain HTTP).
+// TTFB is measured from the moment the end of the request was written to the first byte of the response,
+// and Body from that first byte until the whole response was read.
+type Phases struct {
+	DNS     time.Duration
+	Connect time.Duration
+	TLS     time.Duration
+	TTFB    time.Duration
+	Body    time.Duration
+}
+
+// Trace holds what was measured while sending a request: the time spent in each phase
+// and the bytes of the request and the response, including the headers.
+// Proxy is the URL of the proxy the request was sent through, if any.
+type Trace struct {
+	Phases        Phases
+	BytesSent     uint64
+	BytesReceived uint64
+	Proxy         string
+}
+
+// tracedConn is a connection opened by the tracing dial func. It measures the exchanges sent over it,
+// each a request and its response, until they are taken by the tracing transport.
+// The first exchange also carries the dial phases of the connection.
+// For TLS connections the bytes are counted before encryption.
+type tracedConn struct {
+	net.Conn
+	dialPhases Phases
+
+	mu       sync.Mutex
+	exchange *connExchange
+}
+
+// connExchange holds what was measured while a request was written to a connection and its response read.
+type connExchange struct {
+	phases        Phases
+	bytesWritten  uint64
+	bytesRead     uint64
+	writtenTime   time.Time
+	firstByteTime time.Time
+	lastByteTime  time.Time
+	// taken is closed once the exchange was taken, so that the next exchange can start.
+	taken chan struct{}
+}
+
+// SetWriteDeadline starts a new exchange, since fasthttp sets the write deadline before writing each request.
+// The connection may be handed to the next request before the previous one took its exchange, so it waits for
+// that at most until the deadline. Past that the previous exchange is dropped.
+func (c *tracedConn) SetWriteDeadline(t time.Time) error {
+	c.mu.Lock()
+	previous := c.exchange
+	c.mu.Unlock()
+
+	if previous != nil {
+		timer := time.NewTimer(time.Until(t))
+		select {
+		case <-previous.taken:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+
+	c.mu.Lock()
+	c.exchange = &connExchange{taken: make(chan struct{})}
+	if previous == nil {
+		c.exchange.phases = c.dialPhases
+	}
+	c.mu.Unlock()
+	return c.Conn.SetWriteDeadline(t)
+}
+
+func (c *tracedConn) Write(b []byte) (int, error) {
+	// The time is taken before writing, since the goroutine may not be scheduled again
+	// until after the response arrived.
+	writeTime := time.Now()
+	n, err := c.Conn.Write(b)
+
+	c.mu.Lock()
+	if c.exchange != nil {
+		c.exchange.bytesWritten += uint64(n)
+		c.exchange.writtenTime = writeTime
+	}
+	c.mu.Unlock()
+	return n, err
+}
+
+func (c *tracedConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	now := time.Now()
+
+	c.mu.Lock()
+	if c.exchange != nil && n > 0 {
+		if c.exchange.firstByteTime.IsZero() {
+			c.exchange.firstByteTime = now
+		}
+		c.exchange.lastByteTime = now
+		c.exchange.bytesRead += uint64(n)
+	}
+	c.mu.Unlock()
+	return n, err
+}
+
+// LocalAddr returns the local address of the connection, which also refers to the connection,
+// so that the tracing transport can find it from the response.
+func (c *tracedConn) LocalAddr() net.Addr {
+	return tracedAddr{Addr: c.Conn.LocalAddr(), conn: c}
+}
+
+// Handshake reports the TLS handshake as done, since the tracing dial func performs it itself.
+// fasthttp skips its own handshake for connections that have this method.
+func (c *tracedConn) Handshake() error {
+	return nil
+}
+
+// takeExchange returns the trace of the current exchange and lets the next one start.
+func (c *tracedConn) takeExchange() Trace {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	exchange := c.exchange
+	if exchange == nil {
+		return Trace{}
+	}
+	select {
+	case <-exchange.taken:
+	default:
+		close(exchange.taken)
+	}
+
+	phases := exchange.phases
+	if !exchange.firstByteTime.IsZero() {
+		phases.TTFB = exchange.firstByteTime.Sub(exchange.writtenTime)
+		phases.Body = exchange.lastByteTime.Sub(exchange.firstByteTime)
+	}
+	return Trace{
+		Phases:        phases,
+		BytesSent:     exchange.bytesWritten,
+		BytesReceived: exchange.bytesRead,
+	}
+}
+
+// tracedAddr is the local address of a tracedConn.
+type tracedAddr struct {
+	net.Addr
+	conn *tracedConn
+}
+
+// tracingTransport is a fasthttp.RoundTripper that sends requests with fasthttp's default transport
+// and traces the requests registered with trace, from the connections they were sent over.
+// Each client has its own transport, which knows the proxy of the client.
+// It is safe for concurrent use.
+type tracingTransport struct {
+	proxy  string
+	traces sync.Map // *fasthttp.Request -> *Trace
+}
+
+func newTracingTransport(proxy string) *tracingTransport {
+	return &tracingTransport{proxy: proxy}
+}
+
+// trace registers the request so that it is traced into the given Trace while it is sent.
+// The returned function unregisters it.
+func (t *tracingTransport) trace(request *fasthttp.Request, trace *Trace) func() {
+	t.traces.Store(request, trace)
+	return func() {
+		t.traces.Delete(request)
+	}
+}
+
+// RoundTrip implements fasthttp.RoundTripper.
+func (t *tracingTransport) RoundTrip(
+	hc *fasthttp.HostClient,
+	req *fasthttp.Request,
+	resp *fasthttp.Response,
+) (bool, error) {
+	retry, err := fasthttp.DefaultTransport.RoundTrip(hc, req, resp)
+	if err != nil {
+		// The connection is closed on errors, so its exchange doesn't need to be taken.
+		return retry, err
+	}
+
+	addr, ok := resp.LocalAddr().(tracedAddr)
+	if !ok {
+		return retry, err
+	}
+	exchange := addr.conn.takeExchange()
+	if value, ok := t.traces.Load(req); ok {
+		// A retried request only keeps the trace of its last attempt.
+		*value.(*Trace) = exchange
+	}
+	return retry, err
+}
@@ -88,6 +88,14 @@ func (body *Body) UnmarshalYAML(unmarshal func(any) error) error {
 	return nil
 }
 
+// MarshalYAML marshals a single body as a string and multiple bodies as a list.
+func (body Body) MarshalYAML() (any, error) {
+	if len(body) == 1 {
+		return body[0], nil
+	}
+	return []string(body), nil
+}
+
 func (body *Body) Set(value string) error {
 	*body = append(*body, value)
 	return nil
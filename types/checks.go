@@ -0,0 +1,103 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/text"
+)
+
+// Check is an assertion on the response of a request. Exactly one of Status, Header,
+// BodyContains, BodyRegex, JSONPath and MaxBodySize must be set.
+// Equals can be set together with Header or JSONPath to compare the value instead of only
+// requiring it to exist; for JSONPath it is compared as JSON (e.g. 1 equals 1.0 but not "1").
+// Name is shown in the report; if it is empty, a name is generated from the check.
+type Check struct {
+	Name         string  `json:"name,omitempty" yaml:"name,omitempty"`
+	Status       []int   `json:"status,omitempty" yaml:"status,omitempty"`
+	Header       string  `json:"header,omitempty" yaml:"header,omitempty"`
+	BodyContains string  `json:"body_contains,omitempty" yaml:"body_contains,omitempty"`
+	BodyRegex    string  `json:"body_regex,omitempty" yaml:"body_regex,omitempty"`
+	JSONPath     string  `json:"json_path,omitempty" yaml:"json_path,omitempty"`
+	Equals       any     `json:"equals,omitempty" yaml:"equals,omitempty"`
+	MaxBodySize  *uint64 `json:"max_body_size,omitempty" yaml:"max_body_size,omitempty"`
+}
+
+// Kinds returns the number of assertions set on the check, which must be exactly one.
+func (check Check) Kinds() int {
+	kinds := 0
+	for _, set := range []bool{
+		len(check.Status) > 0,
+		check.Header != "",
+		check.BodyContains != "",
+		check.BodyRegex != "",
+		check.JSONPath != "",
+		check.MaxBodySize != nil,
+	} {
+		if set {
+			kinds++
+		}
+	}
+	return kinds
+}
+
+func (check Check) String() string {
+	if check.Name != "" {
+		return check.Name
+	}
+
+	switch {
+	case len(check.Status) == 1:
+		return fmt.Sprintf("status == %d", check.Status[0])
+	case len(check.Status) > 1:
+		statuses := make([]string, len(check.Status))
+		for i, status := range check.Status {
+			statuses[i] = strconv.Itoa(status)
+		}
+		return "status in [" + strings.Join(statuses, ", ") + "]"
+	case check.Header != "" && check.Equals != nil:
+		return fmt.Sprintf("header %s == %v", check.Header, check.Equals)
+	case check.Header != "":
+		return fmt.Sprintf("header %s exists", check.Header)
+	case check.BodyContains != "":
+		return fmt.Sprintf("body contains %q", check.BodyContains)
+	case check.BodyRegex != "":
+		return fmt.Sprintf("body matches /%s/", check.BodyRegex)
+	case check.JSONPath != "" && check.Equals != nil:
+		equals, _ := json.Marshal(check.Equals)
+		return fmt.Sprintf("json %s == %s", check.JSONPath, equals)
+	case check.JSONPath != "":
+		return fmt.Sprintf("json %s exists", check.JSONPath)
+	case check.MaxBodySize != nil:
+		return fmt.Sprintf("body size <= %d", *check.MaxBodySize)
+	}
+	return ""
+}
+
+type Checks []Check
+
+func (checks Checks) String() string {
+	var buffer bytes.Buffer
+	if len(checks) == 0 {
+		return buffer.String()
+	}
+
+	displayLimit := 5
+
+	for i, check := range checks[:min(len(checks), displayLimit)] {
+		if i > 0 {
+			buffer.WriteString(",\n")
+		}
+		buffer.WriteString(check.String())
+	}
+
+	// Add remaining count if there are more items
+	if remainingValues := len(checks) - displayLimit; remainingValues > 0 {
+		buffer.WriteString(",\n" + text.FgGreen.Sprintf("+%d checks", remainingValues))
+	}
+
+	return buffer.String()
+}
@@ -73,6 +73,14 @@ func (cookies Cookies) GetValue(key string) *[]string {
 	return nil
 }
 
+func (cookies Cookies) MarshalJSON() ([]byte, error) {
+	return marshalKeyValues(cookies)
+}
+
+func (cookies Cookies) MarshalYAML() (any, error) {
+	return keyValuesData(cookies), nil
+}
+
 func (cookies *Cookies) UnmarshalJSON(b []byte) error {
 	var data []map[string]any
 	if err := json.Unmarshal(b, &data); err != nil {
@@ -0,0 +1,19 @@
+package types
+
+import "fmt"
+
+// DataSource is a CSV or JSONL file whose rows the request templates can use as {{ .row.<column> }}.
+// The format is chosen by the extension of the file: .csv files must have a header row
+// with the column names, and each line of .jsonl files is a JSON object.
+// Mode sets how the dodos pick the row of each iteration.
+type DataSource struct {
+	File string `json:"file,omitempty" yaml:"file,omitempty"`
+	Mode string `json:"mode,omitempty" yaml:"mode,omitempty"`
+}
+
+func (source DataSource) String() string {
+	if source.Mode == "" {
+		return source.File
+	}
+	return fmt.Sprintf("%s (%s)", source.File, source.Mode)
+}
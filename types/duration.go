@@ -35,6 +35,10 @@ func (duration Duration) MarshalJSON() ([]byte, error) {
 	return json.Marshal(duration.String())
 }
 
+func (duration Duration) MarshalYAML() (any, error) {
+	return duration.String(), nil
+}
+
 func (duration *Duration) UnmarshalYAML(unmarshal func(any) error) error {
 	var v any
 	if err := unmarshal(&v); err != nil {
@@ -0,0 +1,78 @@
+package types
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/jedib0t/go-pretty/v6/text"
+)
+
+// Extractor captures a value from the response of a request into a variable, which the
+// templates of the later requests of the same dodo can use as {{ .vars.<name> }}.
+// Exactly one of JSONPath, Regex, Header and Cookie must be set. For Regex, the first
+// capture group is extracted if the expression has one, otherwise the whole match.
+// If the value can't be extracted, the variable is set to Default if it is given,
+// otherwise it keeps its previous value.
+type Extractor struct {
+	Var      string  `json:"var" yaml:"var"`
+	JSONPath string  `json:"json_path,omitempty" yaml:"json_path,omitempty"`
+	Regex    string  `json:"regex,omitempty" yaml:"regex,omitempty"`
+	Header   string  `json:"header,omitempty" yaml:"header,omitempty"`
+	Cookie   string  `json:"cookie,omitempty" yaml:"cookie,omitempty"`
+	Default  *string `json:"default,omitempty" yaml:"default,omitempty"`
+}
+
+// Kinds returns the number of sources set on the extractor, which must be exactly one.
+func (extractor Extractor) Kinds() int {
+	kinds := 0
+	for _, set := range []bool{
+		extractor.JSONPath != "",
+		extractor.Regex != "",
+		extractor.Header != "",
+		extractor.Cookie != "",
+	} {
+		if set {
+			kinds++
+		}
+	}
+	return kinds
+}
+
+func (extractor Extractor) String() string {
+	switch {
+	case extractor.JSONPath != "":
+		return fmt.Sprintf("%s = json %s", extractor.Var, extractor.JSONPath)
+	case extractor.Regex != "":
+		return fmt.Sprintf("%s = body /%s/", extractor.Var, extractor.Regex)
+	case extractor.Header != "":
+		return fmt.Sprintf("%s = header %s", extractor.Var, extractor.Header)
+	case extractor.Cookie != "":
+		return fmt.Sprintf("%s = cookie %s", extractor.Var, extractor.Cookie)
+	}
+	return extractor.Var
+}
+
+type Extractors []Extractor
+
+func (extractors Extractors) String() string {
+	var buffer bytes.Buffer
+	if len(extractors) == 0 {
+		return buffer.String()
+	}
+
+	displayLimit := 5
+
+	for i, extractor := range extractors[:min(len(extractors), displayLimit)] {
+		if i > 0 {
+			buffer.WriteString(",\n")
+		}
+		buffer.WriteString(extractor.String())
+	}
+
+	// Add remaining count if there are more items
+	if remainingValues := len(extractors) - displayLimit; remainingValues > 0 {
+		buffer.WriteString(",\n" + text.FgGreen.Sprintf("+%d extractors", remainingValues))
+	}
+
+	return buffer.String()
+}
@@ -82,6 +82,14 @@ func (headers Headers) Has(key string) bool {
 	return false
 }
 
+func (headers Headers) MarshalJSON() ([]byte, error) {
+	return marshalKeyValues(headers)
+}
+
+func (headers Headers) MarshalYAML() (any, error) {
+	return keyValuesData(headers), nil
+}
+
 func (headers *Headers) UnmarshalJSON(b []byte) error {
 	var data []map[string]any
 	if err := json.Unmarshal(b, &data); err != nil {
@@ -0,0 +1,200 @@
+package types
+
+import (
+	"math"
+	"math/bits"
+	"time"
+)
+
+// Histogram records durations with a fixed number of significant figures and constant memory,
+// using the log-linear bucket layout of HdrHistogram.
+//
+// Values are grouped into buckets whose size doubles from one bucket to the next, and each
+// bucket is divided into linear sub-buckets, so every recorded value is kept with a relative
+// error of at most 10^-significantFigures. Buckets are allocated on first use, so only the
+// value ranges that were actually recorded take up memory.
+// Histogram isn't thread-safe; use one histogram per goroutine and merge them with Merge.
+type Histogram struct {
+	significantFigures          int
+	subBucketCount              int64
+	subBucketHalfCount          int64
+	subBucketHalfCountMagnitude int
+	subBucketMask               int64
+
+	// buckets[0] holds subBucketCount counts, every other bucket holds only the upper
+	// half of its sub-buckets, since its lower half overlaps with the previous bucket.
+	buckets [][]uint64
+
+	count uint64
+	min   time.Duration
+	max   time.Duration
+	sum   float64
+}
+
+// NewHistogram creates an empty Histogram that keeps the given number of significant figures (1-5).
+func NewHistogram(significantFigures int) *Histogram {
+	significantFigures = max(1, min(significantFigures, 5))
+
+	largestValueWithSingleUnitResolution := 2 * math.Pow10(significantFigures)
+	subBucketCountMagnitude := int(math.Ceil(math.Log2(largestValueWithSingleUnitResolution)))
+	subBucketCount := int64(1) << subBucketCountMagnitude
+
+	return &Histogram{
+		significantFigures:          significantFigures,
+		subBucketCount:              subBucketCount,
+		subBucketHalfCount:          subBucketCount / 2,
+		subBucketHalfCountMagnitude: subBucketCountMagnitude - 1,
+		subBucketMask:               subBucketCount - 1,
+	}
+}
+
+// SignificantFigures returns the number of significant figures kept by the histogram.
+func (h *Histogram) SignificantFigures() int {
+	return h.significantFigures
+}
+
+// Record adds the given duration to the histogram. Negative durations are recorded as 0.
+func (h *Histogram) Record(value time.Duration) {
+	h.RecordN(value, 1)
+}
+
+// RecordN adds the given duration to the histogram n times. Negative durations are recorded as 0.
+func (h *Histogram) RecordN(value time.Duration, n uint64) {
+	if n == 0 {
+		return
+	}
+	value = max(value, 0)
+
+	bucketIndex, subBucketIndex := h.indexes(int64(value))
+	for len(h.buckets) <= bucketIndex {
+		h.buckets = append(h.buckets, nil)
+	}
+	if h.buckets[bucketIndex] == nil {
+		if bucketIndex == 0 {
+			h.buckets[bucketIndex] = make([]uint64, h.subBucketCount)
+		} else {
+			h.buckets[bucketIndex] = make([]uint64, h.subBucketHalfCount)
+		}
+	}
+	h.buckets[bucketIndex][h.offset(bucketIndex, subBucketIndex)] += n
+
+	if h.count == 0 || value < h.min {
+		h.min = value
+	}
+	if value > h.max {
+		h.max = value
+	}
+	h.count += n
+	h.sum += float64(value) * float64(n)
+}
+
+// Merge adds all the values recorded in the other histogram to this one.
+func (h *Histogram) Merge(other *Histogram) {
+	if other == nil || other.count == 0 {
+		return
+	}
+
+	prevCount, prevMin, prevSum := h.count, h.min, h.sum
+	other.forEach(func(value time.Duration, count uint64) {
+		h.RecordN(value, count)
+	})
+
+	// Keep the exact extremes and sum of the other histogram instead of their bucket equivalents.
+	if prevCount == 0 {
+		h.min = other.min
+	} else {
+		h.min = min(prevMin, other.min)
+	}
+	h.max = max(h.max, other.max)
+	h.sum = prevSum + other.sum
+}
+
+// Count returns the number of recorded values.
+func (h *Histogram) Count() uint64 {
+	return h.count
+}
+
+// Min returns the smallest recorded value.
+func (h *Histogram) Min() time.Duration {
+	return h.min
+}
+
+// Max returns the largest recorded value.
+func (h *Histogram) Max() time.Duration {
+	return h.max
+}
+
+// Mean returns the average of the recorded values.
+func (h *Histogram) Mean() time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	return time.Duration(h.sum / float64(h.count))
+}
+
+// Percentile returns the value below which the given percentage (0-100) of the recorded values fall.
+// The result is the highest value that is equivalent to the matching bucket, capped at Max.
+func (h *Histogram) Percentile(percentile float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+
+	percentile = max(0, min(percentile, 100))
+	// The percentile is multiplied before dividing, so that the rank of percentiles such as 99.9
+	// isn't rounded up to the next value.
+	countAtPercentile := max(uint64(math.Ceil(percentile*float64(h.count)/100)), 1)
+
+	var (
+		total  uint64
+		result = h.max
+		found  bool
+	)
+	h.forEach(func(value time.Duration, count uint64) {
+		if found {
+			return
+		}
+		total += count
+		if total >= countAtPercentile {
+			result = min(h.highestEquivalentValue(value), h.max)
+			found = true
+		}
+	})
+	return result
+}
+
+// indexes returns the bucket and sub-bucket indexes of the given value.
+func (h *Histogram) indexes(value int64) (int, int64) {
+	pow2Ceiling := 64 - bits.LeadingZeros64(uint64(value|h.subBucketMask))
+	bucketIndex := pow2Ceiling - (h.subBucketHalfCountMagnitude + 1)
+	return bucketIndex, value >> bucketIndex
+}
+
+// offset returns the position of the sub-bucket in the counts slice of its bucket.
+func (h *Histogram) offset(bucketIndex int, subBucketIndex int64) int64 {
+	if bucketIndex == 0 {
+		return subBucketIndex
+	}
+	return subBucketIndex - h.subBucketHalfCount
+}
+
+// highestEquivalentValue returns the largest value that falls into the same sub-bucket as the given value.
+func (h *Histogram) highestEquivalentValue(value time.Duration) time.Duration {
+	bucketIndex, _ := h.indexes(int64(value))
+	return value + time.Duration(int64(1)<<bucketIndex) - 1
+}
+
+// forEach calls fn with the lowest value and count of every non-empty sub-bucket, in ascending order.
+func (h *Histogram) forEach(fn func(value time.Duration, count uint64)) {
+	for bucketIndex, counts := range h.buckets {
+		for i, count := range counts {
+			if count == 0 {
+				continue
+			}
+			subBucketIndex := int64(i)
+			if bucketIndex > 0 {
+				subBucketIndex += h.subBucketHalfCount
+			}
+			fn(time.Duration(subBucketIndex<<bucketIndex), count)
+		}
+	}
+}
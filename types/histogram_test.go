@@ -0,0 +1,219 @@
+package types
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestNewHistogramLayout(t *testing.T) {
+	tests := []struct {
+		significantFigures     int
+		wantSignificantFigures int
+		wantSubBucketCount     int64
+	}{
+		{significantFigures: -1, wantSignificantFigures: 1, wantSubBucketCount: 32},
+		{significantFigures: 1, wantSignificantFigures: 1, wantSubBucketCount: 32},
+		{significantFigures: 2, wantSignificantFigures: 2, wantSubBucketCount: 256},
+		{significantFigures: 3, wantSignificantFigures: 3, wantSubBucketCount: 2048},
+		{significantFigures: 4, wantSignificantFigures: 4, wantSubBucketCount: 32768},
+		{significantFigures: 5, wantSignificantFigures: 5, wantSubBucketCount: 262144},
+		{significantFigures: 9, wantSignificantFigures: 5, wantSubBucketCount: 262144},
+	}
+
+	for _, test := range tests {
+		h := NewHistogram(test.significantFigures)
+		if h.SignificantFigures() != test.wantSignificantFigures {
+			t.Errorf("NewHistogram(%d).SignificantFigures() = %d, want %d",
+				test.significantFigures, h.SignificantFigures(), test.wantSignificantFigures)
+		}
+		if h.subBucketCount != test.wantSubBucketCount {
+			t.Errorf("NewHistogram(%d).subBucketCount = %d, want %d",
+				test.significantFigures, h.subBucketCount, test.wantSubBucketCount)
+		}
+		if h.subBucketHalfCount != test.wantSubBucketCount/2 {
+			t.Errorf("NewHistogram(%d).subBucketHalfCount = %d, want %d",
+				test.significantFigures, h.subBucketHalfCount, test.wantSubBucketCount/2)
+		}
+	}
+}
+
+func TestHistogramIndexes(t *testing.T) {
+	// With 2 significant figures there are 256 sub-buckets, the first bucket holds the values
+	// 0-255 exactly, and every later bucket holds the next power of two range in 128 sub-buckets.
+	h := NewHistogram(2)
+
+	tests := []struct {
+		value              int64
+		wantBucket         int
+		wantSubBucket      int64
+		wantOffset         int64
+		wantHighestEqValue time.Duration
+	}{
+		{value: 0, wantBucket: 0, wantSubBucket: 0, wantOffset: 0, wantHighestEqValue: 0},
+		{value: 1, wantBucket: 0, wantSubBucket: 1, wantOffset: 1, wantHighestEqValue: 1},
+		{value: 255, wantBucket: 0, wantSubBucket: 255, wantOffset: 255, wantHighestEqValue: 255},
+		{value: 256, wantBucket: 1, wantSubBucket: 128, wantOffset: 0, wantHighestEqValue: 257},
+		{value: 511, wantBucket: 1, wantSubBucket: 255, wantOffset: 127, wantHighestEqValue: 512},
+		{value: 512, wantBucket: 2, wantSubBucket: 128, wantOffset: 0, wantHighestEqValue: 515},
+		{value: 1000, wantBucket: 2, wantSubBucket: 250, wantOffset: 122, wantHighestEqValue: 1003},
+		{value: 1 << 20, wantBucket: 13, wantSubBucket: 128, wantOffset: 0, wantHighestEqValue: 1<<20 + 1<<13 - 1},
+	}
+
+	for _, test := range tests {
+		bucket, subBucket := h.indexes(test.value)
+		if bucket != test.wantBucket || subBucket != test.wantSubBucket {
+			t.Errorf("indexes(%d) = (%d, %d), want (%d, %d)",
+				test.value, bucket, subBucket, test.wantBucket, test.wantSubBucket)
+			continue
+		}
+		if offset := h.offset(bucket, subBucket); offset != test.wantOffset {
+			t.Errorf("offset(%d, %d) = %d, want %d", bucket, subBucket, offset, test.wantOffset)
+		}
+		if value := h.highestEquivalentValue(time.Duration(test.value)); value != test.wantHighestEqValue {
+			t.Errorf("highestEquivalentValue(%d) = %d, want %d", test.value, value, test.wantHighestEqValue)
+		}
+	}
+}
+
+func TestHistogramPercentile(t *testing.T) {
+	tests := []struct {
+		name               string
+		significantFigures int
+		values             []time.Duration
+		percentile         float64
+		want               time.Duration
+	}{
+		{name: "empty", significantFigures: 3, percentile: 50, want: 0},
+		{name: "single value", significantFigures: 3, values: []time.Duration{42}, percentile: 99, want: 42},
+		{name: "exact p0", significantFigures: 3, values: durationRange(1, 1000), percentile: 0, want: 1},
+		{name: "exact p50", significantFigures: 3, values: durationRange(1, 1000), percentile: 50, want: 500},
+		{name: "exact p99", significantFigures: 3, values: durationRange(1, 1000), percentile: 99, want: 990},
+		{name: "exact p99.9", significantFigures: 3, values: durationRange(1, 1000), percentile: 99.9, want: 999},
+		{name: "exact p100", significantFigures: 3, values: durationRange(1, 1000), percentile: 100, want: 1000},
+		{name: "above 100 is p100", significantFigures: 3, values: durationRange(1, 1000), percentile: 150, want: 1000},
+		{name: "below 0 is p0", significantFigures: 3, values: durationRange(1, 1000), percentile: -5, want: 1},
+		{
+			// With 1 significant figure 300ms is in a bucket of 2^24ns wide sub-buckets,
+			// so it's reported as the highest value of its sub-bucket.
+			name:               "bucketed value",
+			significantFigures: 1,
+			values:             []time.Duration{time.Millisecond, 300 * time.Millisecond, time.Second},
+			percentile:         50,
+			want:               300*time.Millisecond - 300*time.Millisecond%(1<<24) + 1<<24 - 1,
+		},
+		{
+			name:               "capped at max",
+			significantFigures: 1,
+			values:             []time.Duration{time.Millisecond, 300 * time.Millisecond},
+			percentile:         100,
+			want:               300 * time.Millisecond,
+		},
+		{name: "negative is zero", significantFigures: 2, values: []time.Duration{-time.Second, 0}, percentile: 100, want: 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := NewHistogram(test.significantFigures)
+			for _, value := range test.values {
+				h.Record(value)
+			}
+			if got := h.Percentile(test.percentile); got != test.want {
+				t.Errorf("Percentile(%v) = %v, want %v", test.percentile, got, test.want)
+			}
+		})
+	}
+}
+
+func TestHistogramPercentileRelativeError(t *testing.T) {
+	for significantFigures := 1; significantFigures <= 5; significantFigures++ {
+		h := NewHistogram(significantFigures)
+		values := make([]time.Duration, 0, 1000)
+		for i := 1; i <= 1000; i++ {
+			value := time.Duration(i) * 997 * time.Microsecond
+			values = append(values, value)
+			h.Record(value)
+		}
+
+		maxError := 1.0
+		for range significantFigures {
+			maxError /= 10
+		}
+		for _, percentile := range []float64{10, 50, 90, 95, 99, 99.9} {
+			want := values[int(math.Round(percentile*10))-1]
+			got := h.Percentile(percentile)
+			if relativeError := float64(got-want) / float64(want); relativeError < 0 || relativeError > maxError {
+				t.Errorf("%d significant figures: Percentile(%v) = %v, want %v within %v", significantFigures, percentile, got, want, maxError)
+			}
+		}
+	}
+}
+
+func TestHistogramStatsAndMerge(t *testing.T) {
+	a, b := NewHistogram(2), NewHistogram(2)
+	a.Record(1001 * time.Microsecond)
+	a.RecordN(3*time.Millisecond, 2)
+	a.RecordN(time.Hour, 0)
+	b.Record(999 * time.Microsecond)
+	b.Record(12345 * time.Microsecond)
+
+	tests := []struct {
+		name      string
+		histogram *Histogram
+		wantCount uint64
+		wantMin   time.Duration
+		wantMax   time.Duration
+		wantMean  time.Duration
+	}{
+		{name: "empty", histogram: NewHistogram(2)},
+		{
+			name:      "recorded",
+			histogram: a,
+			wantCount: 3,
+			wantMin:   1001 * time.Microsecond,
+			wantMax:   3 * time.Millisecond,
+			wantMean:  (1001*time.Microsecond + 6*time.Millisecond) / 3,
+		},
+		{
+			name: "merged",
+			histogram: func() *Histogram {
+				merged := NewHistogram(2)
+				merged.Merge(a)
+				merged.Merge(b)
+				merged.Merge(nil)
+				merged.Merge(NewHistogram(2))
+				return merged
+			}(),
+			wantCount: 5,
+			wantMin:   999 * time.Microsecond,
+			wantMax:   12345 * time.Microsecond,
+			wantMean:  (1001*time.Microsecond + 6*time.Millisecond + 999*time.Microsecond + 12345*time.Microsecond) / 5,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := test.histogram
+			if h.Count() != test.wantCount {
+				t.Errorf("Count() = %d, want %d", h.Count(), test.wantCount)
+			}
+			if h.Min() != test.wantMin {
+				t.Errorf("Min() = %v, want %v", h.Min(), test.wantMin)
+			}
+			if h.Max() != test.wantMax {
+				t.Errorf("Max() = %v, want %v", h.Max(), test.wantMax)
+			}
+			if h.Mean() != test.wantMean {
+				t.Errorf("Mean() = %v, want %v", h.Mean(), test.wantMean)
+			}
+		})
+	}
+}
+
+func durationRange(from, to time.Duration) []time.Duration {
+	values := make([]time.Duration, 0, to-from+1)
+	for value := from; value <= to; value++ {
+		values = append(values, value)
+	}
+	return values
+}
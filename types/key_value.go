@@ -1,6 +1,28 @@
 package types
 
+import "encoding/json"
+
 type KeyValue[K comparable, V any] struct {
 	Key   K
 	Value V
 }
+
+// marshalKeyValues marshals the key-value pairs in the config file format:
+// a list of single key objects, whose value is a string or a list of strings.
+func marshalKeyValues(items []KeyValue[string, []string]) ([]byte, error) {
+	return json.Marshal(keyValuesData(items))
+}
+
+// keyValuesData returns the key-value pairs in the config file format,
+// ready to be marshaled by both encoding/json and yaml.
+func keyValuesData(items []KeyValue[string, []string]) []map[string]any {
+	data := make([]map[string]any, len(items))
+	for i, item := range items {
+		if len(item.Value) == 1 {
+			data[i] = map[string]any{item.Key: item.Value[0]}
+		} else {
+			data[i] = map[string]any{item.Key: item.Value}
+		}
+	}
+	return data
+}
@@ -73,6 +73,14 @@ func (params Params) GetValue(key string) *[]string {
 	return nil
 }
 
+func (params Params) MarshalJSON() ([]byte, error) {
+	return marshalKeyValues(params)
+}
+
+func (params Params) MarshalYAML() (any, error) {
+	return keyValuesData(params), nil
+}
+
 func (params *Params) UnmarshalJSON(b []byte) error {
 	var data []map[string]any
 	if err := json.Unmarshal(b, &data); err != nil {
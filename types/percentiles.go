@@ -0,0 +1,59 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Percentiles []float64
+
+func (percentiles Percentiles) String() string {
+	values := make([]string, len(percentiles))
+	for i, percentile := range percentiles {
+		values[i] = FormatPercentile(percentile)
+	}
+	return strings.Join(values, ", ")
+}
+
+func (percentiles *Percentiles) UnmarshalJSON(b []byte) error {
+	var data []float64
+	if err := json.Unmarshal(b, &data); err != nil {
+		return fmt.Errorf("invalid type for Percentiles (should be [number]): %v", err)
+	}
+
+	*percentiles = data
+	return nil
+}
+
+func (percentiles *Percentiles) UnmarshalYAML(unmarshal func(any) error) error {
+	var data []float64
+	if err := unmarshal(&data); err != nil {
+		return fmt.Errorf("invalid type for Percentiles (should be [number]): %v", err)
+	}
+
+	*percentiles = data
+	return nil
+}
+
+// Set parses a comma separated list of percentiles (e.g. "50,90,99.9").
+// Each call replaces the previously set percentiles.
+func (percentiles *Percentiles) Set(value string) error {
+	var parsed Percentiles
+	for item := range strings.SplitSeq(value, ",") {
+		percentile, err := strconv.ParseFloat(strings.TrimSpace(item), 64)
+		if err != nil {
+			return fmt.Errorf("invalid percentile \"%s\"", item)
+		}
+		parsed = append(parsed, percentile)
+	}
+
+	*percentiles = parsed
+	return nil
+}
+
+// FormatPercentile formats a percentile as a column name (e.g. 99.9 as "P99.9").
+func FormatPercentile(percentile float64) string {
+	return "P" + strconv.FormatFloat(percentile, 'f', -1, 64)
+}
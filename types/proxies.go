@@ -45,6 +45,22 @@ func (proxies Proxies) String() string {
 	return buffer.String()
 }
 
+func (proxies Proxies) MarshalJSON() ([]byte, error) {
+	data := make([]string, len(proxies))
+	for i, proxy := range proxies {
+		data[i] = proxy.String()
+	}
+	return json.Marshal(data)
+}
+
+func (proxies Proxies) MarshalYAML() (any, error) {
+	data := make([]string, len(proxies))
+	for i, proxy := range proxies {
+		data[i] = proxy.String()
+	}
+	return data, nil
+}
+
 func (proxies *Proxies) UnmarshalJSON(b []byte) error {
 	var data any
 	if err := json.Unmarshal(b, &data); err != nil {
@@ -0,0 +1,83 @@
+package types
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/jedib0t/go-pretty/v6/text"
+)
+
+// RequestDefinition describes one of the requests of a run, such as a step of a scenario.
+// Unset method, URL and body fall back to the top-level request of the config, and the
+// vars, params, headers, cookies, checks and extractors are used in addition to the top-level ones.
+// A relative URL (e.g. "/items") is resolved against the top-level URL.
+// Weight is the relative share of the request in a weighted mix; it defaults to 1.
+type RequestDefinition struct {
+	Name    string      `json:"name,omitempty" yaml:"name,omitempty"`
+	Weight  *uint       `json:"weight,omitempty" yaml:"weight,omitempty"`
+	Method  string      `json:"method,omitempty" yaml:"method,omitempty"`
+	URL     *RequestURL `json:"url,omitempty" yaml:"url,omitempty"`
+	Vars    Vars        `json:"vars,omitempty" yaml:"vars,omitempty"`
+	Params  Params      `json:"params,omitempty" yaml:"params,omitempty"`
+	Headers Headers     `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Cookies Cookies     `json:"cookies,omitempty" yaml:"cookies,omitempty"`
+	Body    Body        `json:"body,omitempty" yaml:"body,omitempty"`
+	Checks  Checks      `json:"checks,omitempty" yaml:"checks,omitempty"`
+	Extract Extractors  `json:"extract,omitempty" yaml:"extract,omitempty"`
+}
+
+func (definition RequestDefinition) String() string {
+	name := definition.name()
+	if definition.Weight != nil {
+		name += fmt.Sprintf(" (weight %d)", *definition.Weight)
+	}
+	return name
+}
+
+func (definition RequestDefinition) name() string {
+	if definition.Name != "" {
+		return definition.Name
+	}
+
+	path := ""
+	if definition.URL != nil {
+		path = definition.URL.String()
+	}
+	if definition.Method == "" {
+		return path
+	}
+	return definition.Method + " " + path
+}
+
+// GetWeight returns the weight of the request in a weighted mix, 1 if it isn't set.
+func (definition RequestDefinition) GetWeight() uint {
+	if definition.Weight == nil {
+		return 1
+	}
+	return *definition.Weight
+}
+
+type RequestDefinitions []RequestDefinition
+
+func (definitions RequestDefinitions) String() string {
+	var buffer bytes.Buffer
+	if len(definitions) == 0 {
+		return buffer.String()
+	}
+
+	displayLimit := 5
+
+	for i, definition := range definitions[:min(len(definitions), displayLimit)] {
+		if i > 0 {
+			buffer.WriteString(",\n")
+		}
+		buffer.WriteString(definition.String())
+	}
+
+	// Add remaining count if there are more items
+	if remainingValues := len(definitions) - displayLimit; remainingValues > 0 {
+		buffer.WriteString(",\n" + text.FgGreen.Sprintf("+%d requests", remainingValues))
+	}
+
+	return buffer.String()
+}
@@ -44,6 +44,10 @@ func (requestURL RequestURL) MarshalJSON() ([]byte, error) {
 	return json.Marshal(requestURL.URL.String())
 }
 
+func (requestURL RequestURL) MarshalYAML() (any, error) {
+	return requestURL.URL.String(), nil
+}
+
 func (requestURL RequestURL) String() string {
 	return requestURL.URL.String()
 }
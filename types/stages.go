@@ -0,0 +1,90 @@
+package types
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/text"
+)
+
+// Stage is a single step of a load profile.
+// During the stage the load is ramped linearly from the previous stage's target
+// to this stage's target, either in dodos (concurrency) or in requests per second.
+type Stage struct {
+	Duration Duration `json:"duration" yaml:"duration"`
+	Dodos    *uint    `json:"dodos,omitempty" yaml:"dodos,omitempty"`
+	Rate     *uint    `json:"rate,omitempty" yaml:"rate,omitempty"`
+}
+
+// TargetsRate reports whether the stage targets requests per second instead of dodos.
+func (stage Stage) TargetsRate() bool {
+	return stage.Rate != nil
+}
+
+// Target returns the dodos or rate target of the stage, whichever is set.
+func (stage Stage) Target() uint {
+	if stage.Rate != nil {
+		return *stage.Rate
+	}
+	if stage.Dodos != nil {
+		return *stage.Dodos
+	}
+	return 0
+}
+
+type Stages []Stage
+
+func (stages Stages) String() string {
+	var buffer bytes.Buffer
+	if len(stages) == 0 {
+		return buffer.String()
+	}
+
+	displayLimit := 5
+
+	for i, stage := range stages[:min(len(stages), displayLimit)] {
+		if i > 0 {
+			buffer.WriteString(",\n")
+		}
+
+		if stage.TargetsRate() {
+			buffer.WriteString(fmt.Sprintf("%v → %d/s", stage.Duration.Duration, stage.Target()))
+		} else {
+			buffer.WriteString(fmt.Sprintf("%v → %d dodos", stage.Duration.Duration, stage.Target()))
+		}
+	}
+
+	// Add remaining count if there are more items
+	if remainingValues := len(stages) - displayLimit; remainingValues > 0 {
+		buffer.WriteString(",\n" + text.FgGreen.Sprintf("+%d stages", remainingValues))
+	}
+
+	return buffer.String()
+}
+
+// TargetsRate reports whether the stages target requests per second instead of dodos.
+func (stages Stages) TargetsRate() bool {
+	return len(stages) > 0 && stages[0].TargetsRate()
+}
+
+// TotalDuration returns the sum of all stage durations.
+func (stages Stages) TotalDuration() time.Duration {
+	total := time.Duration(0)
+	for _, stage := range stages {
+		total += stage.Duration.Duration
+	}
+	return total
+}
+
+// MaxDodos returns the highest dodos target among the stages.
+// It returns 0 if the stages target requests per second.
+func (stages Stages) MaxDodos() uint {
+	maxDodos := uint(0)
+	for _, stage := range stages {
+		if stage.Dodos != nil {
+			maxDodos = max(maxDodos, *stage.Dodos)
+		}
+	}
+	return maxDodos
+}
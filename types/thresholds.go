@@ -0,0 +1,240 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ThresholdPercentile string = "percentile"
+	ThresholdAverage    string = "avg"
+	ThresholdMin        string = "min"
+	ThresholdMax        string = "max"
+	ThresholdRPS        string = "rps"
+	ThresholdCount      string = "count"
+	ThresholdRatio      string = "ratio"
+	ThresholdErrorRate  string = "error_rate"
+	ThresholdErrors     string = "errors"
+	ThresholdCheckRate  string = "failed_check_rate"
+	ThresholdChecks     string = "failed_checks"
+)
+
+var (
+	thresholdRegexp      = regexp.MustCompile(`^([a-z_]+|p[0-9.]+)(?:\[([^\]]+)\])?\s*(<=|>=|==|<|>)\s*(\S+)$`)
+	thresholdScopeRegexp = regexp.MustCompile(`^(?i:[1-5][0-9]{2}|[1-5]xx|errors)$`)
+)
+
+// Threshold is a pass/fail rule evaluated on the result of a run, in the form of
+// "<metric>[<scope>] <operator> <value>" (e.g. "p95 < 300ms", "count[5xx] == 0").
+//
+// The latency metrics (p<N>, avg, min, max) are compared with durations (Value is in nanoseconds),
+// error_rate, failed_check_rate and ratio with ratios written as percents or fractions
+// (Value is between 0 and 1), and rps, count, errors and failed_checks with plain numbers.
+// Scope limits the metric to a status code (e.g. "500"), a status group (e.g. "5xx")
+// or the requests that failed without a response ("errors"); it is empty for all responses.
+type Threshold struct {
+	Metric     string
+	Percentile float64
+	Scope      string
+	Operator   string
+	Value      float64
+	expression string
+}
+
+// ParseThreshold parses a threshold expression such as "p99.9 <= 1s" or "error_rate < 1%".
+func ParseThreshold(expression string) (Threshold, error) {
+	expression = strings.TrimSpace(expression)
+	matches := thresholdRegexp.FindStringSubmatch(expression)
+	if matches == nil {
+		return Threshold{}, fmt.Errorf("invalid threshold \"%s\" (should be \"<metric> <operator> <value>\", e.g. \"p95 < 300ms\")", expression)
+	}
+
+	threshold := Threshold{
+		Metric:     matches[1],
+		Scope:      matches[2],
+		Operator:   matches[3],
+		expression: expression,
+	}
+
+	switch threshold.Metric {
+	case ThresholdAverage, ThresholdMin, ThresholdMax, ThresholdRPS, ThresholdCount, ThresholdRatio:
+	case ThresholdErrorRate, ThresholdErrors, ThresholdCheckRate, ThresholdChecks:
+		if threshold.Scope != "" {
+			return Threshold{}, fmt.Errorf("invalid threshold \"%s\": %s cannot be limited to a status", expression, threshold.Metric)
+		}
+	default:
+		percentile, err := strconv.ParseFloat(strings.TrimPrefix(threshold.Metric, "p"), 64)
+		if !strings.HasPrefix(threshold.Metric, "p") || err != nil || percentile <= 0 || percentile > 100 {
+			return Threshold{}, fmt.Errorf(
+				"invalid threshold \"%s\": unknown metric \"%s\" (supported metrics: p<N>, avg, min, max, rps, count, ratio, error_rate, errors, failed_check_rate, failed_checks)",
+				expression, threshold.Metric,
+			)
+		}
+		threshold.Metric = ThresholdPercentile
+		threshold.Percentile = percentile
+	}
+
+	if threshold.Scope != "" && !thresholdScopeRegexp.MatchString(threshold.Scope) {
+		return Threshold{}, fmt.Errorf(
+			"invalid threshold \"%s\": unknown status \"%s\" (should be a status code, a status group like 5xx or errors)",
+			expression, threshold.Scope,
+		)
+	}
+
+	value := matches[4]
+	switch {
+	case threshold.IsLatency():
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return Threshold{}, fmt.Errorf("invalid threshold \"%s\": %s should be compared with a duration (e.g. 300ms)", expression, matches[1])
+		}
+		threshold.Value = float64(duration)
+	case threshold.IsRatio():
+		ratio, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		if err != nil {
+			return Threshold{}, fmt.Errorf("invalid threshold \"%s\": %s should be compared with a percent (e.g. 1%%)", expression, matches[1])
+		}
+		if strings.HasSuffix(value, "%") {
+			ratio /= 100
+		}
+		threshold.Value = ratio
+	default:
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return Threshold{}, fmt.Errorf("invalid threshold \"%s\": %s should be compared with a number", expression, matches[1])
+		}
+		threshold.Value = number
+	}
+
+	return threshold, nil
+}
+
+func (threshold Threshold) String() string {
+	return threshold.expression
+}
+
+// IsLatency reports whether the metric of the threshold is a latency.
+func (threshold Threshold) IsLatency() bool {
+	switch threshold.Metric {
+	case ThresholdPercentile, ThresholdAverage, ThresholdMin, ThresholdMax:
+		return true
+	}
+	return false
+}
+
+// IsRatio reports whether the metric of the threshold is a ratio.
+func (threshold Threshold) IsRatio() bool {
+	switch threshold.Metric {
+	case ThresholdRatio, ThresholdErrorRate, ThresholdCheckRate:
+		return true
+	}
+	return false
+}
+
+// Passes reports whether the actual value of the metric satisfies the threshold.
+func (threshold Threshold) Passes(actual float64) bool {
+	switch threshold.Operator {
+	case "<":
+		return actual < threshold.Value
+	case "<=":
+		return actual <= threshold.Value
+	case ">":
+		return actual > threshold.Value
+	case ">=":
+		return actual >= threshold.Value
+	case "==":
+		return actual == threshold.Value
+	}
+	return false
+}
+
+// FormatValue formats a value of the metric of the threshold
+// as a duration, a percent or a number, depending on the metric.
+func (threshold Threshold) FormatValue(value float64) string {
+	switch {
+	case threshold.IsLatency():
+		return time.Duration(value).String()
+	case threshold.IsRatio():
+		return fmt.Sprintf("%.2f%%", value*100)
+	case threshold.Metric == ThresholdRPS:
+		return fmt.Sprintf("%.2f", value)
+	}
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+type Thresholds []Threshold
+
+func (thresholds Thresholds) String() string {
+	var buffer bytes.Buffer
+	for i, threshold := range thresholds {
+		if i > 0 {
+			buffer.WriteString(",\n")
+		}
+		buffer.WriteString(threshold.String())
+	}
+	return buffer.String()
+}
+
+func (thresholds *Thresholds) parse(expressions []string) error {
+	parsed := make(Thresholds, 0, len(expressions))
+	for _, expression := range expressions {
+		threshold, err := ParseThreshold(expression)
+		if err != nil {
+			return err
+		}
+		parsed = append(parsed, threshold)
+	}
+
+	*thresholds = parsed
+	return nil
+}
+
+func (thresholds *Thresholds) UnmarshalJSON(b []byte) error {
+	var data []string
+	if err := json.Unmarshal(b, &data); err != nil {
+		return fmt.Errorf("invalid type for Thresholds (should be [string]): %v", err)
+	}
+	return thresholds.parse(data)
+}
+
+func (thresholds *Thresholds) UnmarshalYAML(unmarshal func(any) error) error {
+	var data []string
+	if err := unmarshal(&data); err != nil {
+		return fmt.Errorf("invalid type for Thresholds (should be [string]): %v", err)
+	}
+	return thresholds.parse(data)
+}
+
+// MarshalJSON encodes the thresholds as their expressions, the same as in the config file.
+func (thresholds Thresholds) MarshalJSON() ([]byte, error) {
+	expressions := make([]string, len(thresholds))
+	for i, threshold := range thresholds {
+		expressions[i] = threshold.String()
+	}
+	return json.Marshal(expressions)
+}
+
+// MarshalYAML encodes the thresholds as their expressions, the same as in the config file.
+func (thresholds Thresholds) MarshalYAML() (any, error) {
+	expressions := make([]string, len(thresholds))
+	for i, threshold := range thresholds {
+		expressions[i] = threshold.String()
+	}
+	return expressions, nil
+}
+
+// Set parses a threshold expression and appends it to the thresholds.
+func (thresholds *Thresholds) Set(value string) error {
+	threshold, err := ParseThreshold(value)
+	if err != nil {
+		return err
+	}
+
+	*thresholds = append(*thresholds, threshold)
+	return nil
+}
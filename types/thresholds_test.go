@@ -0,0 +1,149 @@
+package types
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseThreshold(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       Threshold
+		wantErr    string
+	}{
+		{
+			expression: "p95 < 300ms",
+			want:       Threshold{Metric: ThresholdPercentile, Percentile: 95, Operator: "<", Value: float64(300 * time.Millisecond)},
+		},
+		{
+			expression: "  p99.9<=1s  ",
+			want:       Threshold{Metric: ThresholdPercentile, Percentile: 99.9, Operator: "<=", Value: float64(time.Second)},
+		},
+		{
+			expression: "p100 < 2s",
+			want:       Threshold{Metric: ThresholdPercentile, Percentile: 100, Operator: "<", Value: float64(2 * time.Second)},
+		},
+		{
+			expression: "avg[200] >= 10ms",
+			want:       Threshold{Metric: ThresholdAverage, Scope: "200", Operator: ">=", Value: float64(10 * time.Millisecond)},
+		},
+		{
+			expression: "max[5xx] > 1m",
+			want:       Threshold{Metric: ThresholdMax, Scope: "5xx", Operator: ">", Value: float64(time.Minute)},
+		},
+		{
+			expression: "count[5xx] == 0",
+			want:       Threshold{Metric: ThresholdCount, Scope: "5xx", Operator: "==", Value: 0},
+		},
+		{
+			expression: "count[errors] < 5",
+			want:       Threshold{Metric: ThresholdCount, Scope: "errors", Operator: "<", Value: 5},
+		},
+		{
+			expression: "rps >= 99.5",
+			want:       Threshold{Metric: ThresholdRPS, Operator: ">=", Value: 99.5},
+		},
+		{
+			expression: "error_rate < 1%",
+			want:       Threshold{Metric: ThresholdErrorRate, Operator: "<", Value: 0.01},
+		},
+		{
+			expression: "ratio[2XX] > 0.95",
+			want:       Threshold{Metric: ThresholdRatio, Scope: "2XX", Operator: ">", Value: 0.95},
+		},
+		{
+			expression: "failed_check_rate <= 50%",
+			want:       Threshold{Metric: ThresholdCheckRate, Operator: "<=", Value: 0.5},
+		},
+		{
+			expression: "failed_checks == 0",
+			want:       Threshold{Metric: ThresholdChecks, Operator: "==", Value: 0},
+		},
+		{expression: "p95 300ms", wantErr: "should be \"<metric> <operator> <value>\""},
+		{expression: "p95 != 300ms", wantErr: "should be \"<metric> <operator> <value>\""},
+		{expression: "", wantErr: "should be \"<metric> <operator> <value>\""},
+		{expression: "median < 1s", wantErr: "unknown metric \"median\""},
+		{expression: "p0 < 1s", wantErr: "unknown metric \"p0\""},
+		{expression: "p101 < 1s", wantErr: "unknown metric \"p101\""},
+		{expression: "p9.9.9 < 1s", wantErr: "unknown metric \"p9.9.9\""},
+		{expression: "errors[5xx] == 0", wantErr: "errors cannot be limited to a status"},
+		{expression: "count[600] == 0", wantErr: "unknown status \"600\""},
+		{expression: "count[4x] == 0", wantErr: "unknown status \"4x\""},
+		{expression: "p95 < 300", wantErr: "should be compared with a duration"},
+		{expression: "error_rate < a%", wantErr: "should be compared with a percent"},
+		{expression: "rps > 1s", wantErr: "should be compared with a number"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			got, err := ParseThreshold(test.expression)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("ParseThreshold(%q) error = %v, want it to contain %q", test.expression, err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseThreshold(%q) unexpected error: %v", test.expression, err)
+			}
+
+			test.want.expression = strings.TrimSpace(test.expression)
+			if got != test.want {
+				t.Errorf("ParseThreshold(%q) = %+v, want %+v", test.expression, got, test.want)
+			}
+		})
+	}
+}
+
+func TestThresholdPasses(t *testing.T) {
+	tests := []struct {
+		expression string
+		actual     float64
+		want       bool
+	}{
+		{"count < 5", 4, true},
+		{"count < 5", 5, false},
+		{"count <= 5", 5, true},
+		{"count <= 5", 6, false},
+		{"count > 5", 6, true},
+		{"count > 5", 5, false},
+		{"count >= 5", 5, true},
+		{"count >= 5", 4, false},
+		{"count == 5", 5, true},
+		{"count == 5", 4, false},
+	}
+
+	for _, test := range tests {
+		threshold, err := ParseThreshold(test.expression)
+		if err != nil {
+			t.Fatalf("ParseThreshold(%q) unexpected error: %v", test.expression, err)
+		}
+		if got := threshold.Passes(test.actual); got != test.want {
+			t.Errorf("%q.Passes(%v) = %v, want %v", test.expression, test.actual, got, test.want)
+		}
+	}
+}
+
+func TestThresholdFormatValue(t *testing.T) {
+	tests := []struct {
+		expression string
+		value      float64
+		want       string
+	}{
+		{"p95 < 1s", float64(1500 * time.Millisecond), "1.5s"},
+		{"error_rate < 1%", 0.0125, "1.25%"},
+		{"rps > 10", 12.345, "12.35"},
+		{"count == 0", 42, "42"},
+	}
+
+	for _, test := range tests {
+		threshold, err := ParseThreshold(test.expression)
+		if err != nil {
+			t.Fatalf("ParseThreshold(%q) unexpected error: %v", test.expression, err)
+		}
+		if got := threshold.FormatValue(test.value); got != test.want {
+			t.Errorf("%q.FormatValue(%v) = %q, want %q", test.expression, test.value, got, test.want)
+		}
+	}
+}
@@ -35,6 +35,10 @@ func (timeout Timeout) MarshalJSON() ([]byte, error) {
 	return json.Marshal(timeout.String())
 }
 
+func (timeout Timeout) MarshalYAML() (any, error) {
+	return timeout.String(), nil
+}
+
 func (timeout *Timeout) UnmarshalYAML(unmarshal func(any) error) error {
 	var v any
 	if err := unmarshal(&v); err != nil {
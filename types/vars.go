@@ -0,0 +1,149 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/text"
+)
+
+// Vars are the variables of a request, evaluated once per request in order, so a variable can use
+// the ones before it. Like params, a variable with multiple values takes a random one of them.
+type Vars []KeyValue[string, []string]
+
+func (vars Vars) String() string {
+	var buffer bytes.Buffer
+	if len(vars) == 0 {
+		return buffer.String()
+	}
+
+	indent := "  "
+
+	displayLimit := 3
+
+	for i, item := range vars[:min(len(vars), displayLimit)] {
+		if i > 0 {
+			buffer.WriteString(",\n")
+		}
+
+		if len(item.Value) == 1 {
+			buffer.WriteString(item.Key + ": " + item.Value[0])
+			continue
+		}
+		buffer.WriteString(item.Key + ": " + text.FgBlue.Sprint("Random") + "[\n")
+
+		for ii, v := range item.Value[:min(len(item.Value), displayLimit)] {
+			if ii == len(item.Value)-1 {
+				buffer.WriteString(indent + v + "\n")
+			} else {
+				buffer.WriteString(indent + v + ",\n")
+			}
+		}
+
+		// Add remaining values count if needed
+		if remainingValues := len(item.Value) - displayLimit; remainingValues > 0 {
+			buffer.WriteString(indent + text.FgGreen.Sprintf("+%d values", remainingValues) + "\n")
+		}
+
+		buffer.WriteString("]")
+	}
+
+	// Add remaining key-value pairs count if needed
+	if remainingPairs := len(vars) - displayLimit; remainingPairs > 0 {
+		buffer.WriteString(",\n" + text.FgGreen.Sprintf("+%d vars", remainingPairs))
+	}
+
+	return buffer.String()
+}
+
+func (vars *Vars) AppendByKey(key, value string) {
+	if item := vars.GetValue(key); item != nil {
+		*item = append(*item, value)
+	} else {
+		*vars = append(*vars, KeyValue[string, []string]{Key: key, Value: []string{value}})
+	}
+}
+
+func (vars Vars) GetValue(key string) *[]string {
+	for i := range vars {
+		if vars[i].Key == key {
+			return &vars[i].Value
+		}
+	}
+	return nil
+}
+
+func (vars Vars) MarshalJSON() ([]byte, error) {
+	return marshalKeyValues(vars)
+}
+
+func (vars Vars) MarshalYAML() (any, error) {
+	return keyValuesData(vars), nil
+}
+
+func (vars *Vars) UnmarshalJSON(b []byte) error {
+	var data []map[string]any
+	if err := json.Unmarshal(b, &data); err != nil {
+		return err
+	}
+
+	for _, item := range data {
+		for key, value := range item {
+			switch parsedValue := value.(type) {
+			case string:
+				*vars = append(*vars, KeyValue[string, []string]{Key: key, Value: []string{parsedValue}})
+			case []any:
+				parsedStr := make([]string, len(parsedValue))
+				for i, item := range parsedValue {
+					parsedStr[i] = fmt.Sprintf("%v", item)
+				}
+				*vars = append(*vars, KeyValue[string, []string]{Key: key, Value: parsedStr})
+			default:
+				return fmt.Errorf("unsupported type for vars expected string or []string, got %T", parsedValue)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (vars *Vars) UnmarshalYAML(unmarshal func(any) error) error {
+	var raw []map[string]any
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+
+	for _, param := range raw {
+		for key, value := range param {
+			switch parsed := value.(type) {
+			case string:
+				*vars = append(*vars, KeyValue[string, []string]{Key: key, Value: []string{parsed}})
+			case []any:
+				var values []string
+				for _, v := range parsed {
+					if str, ok := v.(string); ok {
+						values = append(values, str)
+					}
+				}
+				*vars = append(*vars, KeyValue[string, []string]{Key: key, Value: values})
+			}
+		}
+	}
+	return nil
+}
+
+func (vars *Vars) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	switch len(parts) {
+	case 0:
+		vars.AppendByKey("", "")
+	case 1:
+		vars.AppendByKey(parts[0], "")
+	case 2:
+		vars.AppendByKey(parts[0], parts[1])
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+)
+
+// LookupJSONPath returns the value at the dot separated path (e.g. "data.items.0.id") in a value
+// decoded by encoding/json. Array elements are addressed by their index, and a leading "$." is ignored.
+// It returns false if the path doesn't exist.
+func LookupJSONPath(value any, path string) (any, bool) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	if path == "" {
+		return value, true
+	}
+
+	for key := range strings.SplitSeq(path, ".") {
+		switch current := value.(type) {
+		case map[string]any:
+			next, ok := current[key]
+			if !ok {
+				return nil, false
+			}
+			value = next
+		case []any:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(current) {
+				return nil, false
+			}
+			value = current[index]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
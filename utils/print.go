@@ -2,6 +2,7 @@ package utils
 
 import (
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/jedib0t/go-pretty/v6/text"
@@ -18,7 +19,7 @@ func PrintErrAndExit(err error) {
 	}
 }
 
-func PrintAndExit(message string) {
-	fmt.Println(message)
+func PrintAndExit(w io.Writer, message string) {
+	fmt.Fprintln(w, message)
 	os.Exit(0)
 }
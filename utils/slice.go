@@ -1,6 +1,9 @@
 package utils
 
-import "math/rand"
+import (
+	"math/rand"
+	"slices"
+)
 
 func Flatten[T any](nested [][]T) []T {
 	flattened := make([]T, 0)
@@ -40,3 +43,26 @@ func RandomValueCycle[T any](values []T, localRand *rand.Rand) func() T {
 		}
 	}
 }
+
+// WeightedRandomValue returns a function that returns a random value of the provided values,
+// each chosen with a probability proportional to its weight.
+// The values and weights must have the same length and the sum of the weights must be greater than 0.
+// This function is not thread-safe and should not be called concurrently.
+func WeightedRandomValue[T any](values []T, weights []uint, localRand *rand.Rand) func() T {
+	if len(values) == 1 {
+		return func() T { return values[0] }
+	}
+
+	cumulativeWeights := make([]int, len(weights))
+	total := 0
+	for i, weight := range weights {
+		total += int(weight)
+		cumulativeWeights[i] = total
+	}
+
+	return func() T {
+		target := localRand.Intn(total)
+		index, _ := slices.BinarySearch(cumulativeWeights, target+1)
+		return values[index]
+	}
+}
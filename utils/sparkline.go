@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"math"
+	"strings"
+)
+
+var sparklineBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline renders the values as a line of block characters scaled between 0 and the largest value.
+// If there are more values than width, adjacent values are averaged so the line fits in width characters.
+func Sparkline(values []float64, width int) string {
+	if len(values) == 0 || width <= 0 {
+		return ""
+	}
+
+	if len(values) > width {
+		averaged := make([]float64, width)
+		for i := range width {
+			from, to := i*len(values)/width, (i+1)*len(values)/width
+			sum := 0.0
+			for _, value := range values[from:to] {
+				sum += value
+			}
+			averaged[i] = sum / float64(to-from)
+		}
+		values = averaged
+	}
+
+	maxValue := 0.0
+	for _, value := range values {
+		maxValue = max(maxValue, value)
+	}
+
+	var sb strings.Builder
+	for _, value := range values {
+		level := 0
+		if maxValue > 0 {
+			level = int(math.Round(value / maxValue * float64(len(sparklineBlocks)-1)))
+		}
+		sb.WriteRune(sparklineBlocks[max(0, min(level, len(sparklineBlocks)-1))])
+	}
+	return sb.String()
+}
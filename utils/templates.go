@@ -2,8 +2,21 @@ package utils
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"maps"
 	"math/rand"
 	"mime/multipart"
+	"net/url"
+	"slices"
 	"strings"
 	"text/template"
 	"time"
@@ -11,14 +24,43 @@ import (
 	"github.com/brianvoe/gofakeit/v7"
 )
 
+// TemplateData is the data the request templates are executed with.
+// The "vars" key holds the variables of a dodo, and the "row" key the row of the data file
+// picked for the current iteration. The "dodo" key holds the index of the dodo, "iter" the number
+// of the current iteration of the dodo, "seq" the number of the request in the run, counted over
+// all dodos, and "elapsed" the time since the start of the run when the request was created.
+// The "body" key holds the rendered body of the request, for the templates of its headers,
+// params and cookies.
+type TemplateData map[string]any
+
+// NewTemplateData returns the template data of a dodo, without any variables or row.
+func NewTemplateData() TemplateData {
+	return TemplateData{
+		"vars":    map[string]string{},
+		"row":     map[string]any{},
+		"dodo":    0,
+		"iter":    0,
+		"seq":     0,
+		"elapsed": time.Duration(0),
+		"body":    "",
+	}
+}
+
+// Vars returns the variables of the template data.
+func (data TemplateData) Vars() map[string]string {
+	return data["vars"].(map[string]string)
+}
+
 type FuncMapGenerator struct {
 	bodyDataHeader string
+	localRand      *rand.Rand
 	localFaker     *gofakeit.Faker
 	funcMap        *template.FuncMap
 }
 
 func NewFuncMapGenerator(localRand *rand.Rand) *FuncMapGenerator {
 	f := &FuncMapGenerator{
+		localRand:  localRand,
 		localFaker: gofakeit.NewFaker(localRand, false),
 	}
 	f.funcMap = f.newFuncMap()
@@ -44,6 +86,10 @@ func (g *FuncMapGenerator) GetFuncMap() *template.FuncMap {
 //
 // All functions are prefixed to avoid naming conflicts:
 //   - String functions: "strings_*"
+//   - Math functions: "math_*"
+//   - Encoding functions: "encoding_*"
+//   - Hash functions: "hash_*" and "hmac_*", which return the raw digest to be encoded
+//   - Time functions: "time_*"
 //   - Dict functions: "dict_*"
 //   - Body functions: "body_*"
 //   - Data generation functions: "fakeit_*"
@@ -85,6 +131,45 @@ func (g *FuncMapGenerator) newFuncMap() *template.FuncMap {
 			return strings.Join(values, sep)
 		},
 
+		// Math
+		"math_Add": func(a, b int) int { return a + b },
+		"math_Sub": func(a, b int) int { return a - b },
+		"math_Mul": func(a, b int) int { return a * b },
+		"math_Div": func(a, b int) (int, error) {
+			if b == 0 {
+				return 0, errors.New("division by zero")
+			}
+			return a / b, nil
+		},
+		"math_Mod": func(a, b int) (int, error) {
+			if b == 0 {
+				return 0, errors.New("division by zero")
+			}
+			return a % b, nil
+		},
+
+		// Encoding
+		"encoding_Base64":    func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) },
+		"encoding_Base64URL": func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) },
+		"encoding_Hex":       func(s string) string { return hex.EncodeToString([]byte(s)) },
+		"encoding_URLQuery":  url.QueryEscape,
+		"encoding_URLPath":   url.PathEscape,
+
+		// Hash
+		"hash_MD5":    func(s string) string { return hashString(md5.New(), s) },
+		"hash_SHA1":   func(s string) string { return hashString(sha1.New(), s) },
+		"hash_SHA256": func(s string) string { return hashString(sha256.New(), s) },
+		"hash_SHA512": func(s string) string { return hashString(sha512.New(), s) },
+		"hmac_MD5":    func(key, s string) string { return hashString(hmac.New(md5.New, []byte(key)), s) },
+		"hmac_SHA1":   func(key, s string) string { return hashString(hmac.New(sha1.New, []byte(key)), s) },
+		"hmac_SHA256": func(key, s string) string { return hashString(hmac.New(sha256.New, []byte(key)), s) },
+		"hmac_SHA512": func(key, s string) string { return hashString(hmac.New(sha512.New, []byte(key)), s) },
+
+		// Time
+		"time_Unix":      func() int64 { return time.Now().Unix() },
+		"time_UnixMilli": func() int64 { return time.Now().UnixMilli() },
+		"time_RFC3339":   func() string { return time.Now().UTC().Format(time.RFC3339) },
+
 		// Dict
 		"dict_Str": func(values ...string) map[string]string {
 			dict := make(map[string]string)
@@ -104,18 +189,38 @@ func (g *FuncMapGenerator) newFuncMap() *template.FuncMap {
 		"slice_Uint": func(values ...uint) []uint { return values },
 
 		// Body
-		"body_FormData": func(kv map[string]string) string {
+		"body_FormData": func(fields any) (string, error) {
 			var data bytes.Buffer
 			writer := multipart.NewWriter(&data)
 
-			for k, v := range kv {
-				_ = writer.WriteField(k, v)
+			// The boundary is taken from the local random number generator, so that the body
+			// is repeated with the same seed.
+			boundary := make([]byte, 30)
+			_, _ = g.localRand.Read(boundary)
+			_ = writer.SetBoundary(hex.EncodeToString(boundary))
+
+			// The fields of a dict are written in the order of their keys, and the fields of
+			// a slice of key-value pairs in their own order.
+			switch fields := fields.(type) {
+			case map[string]string:
+				for _, k := range slices.Sorted(maps.Keys(fields)) {
+					_ = writer.WriteField(k, fields[k])
+				}
+			case []string:
+				if len(fields)%2 != 0 {
+					return "", errors.New("body_FormData: the slice must have a value for every key")
+				}
+				for i := 0; i < len(fields); i += 2 {
+					_ = writer.WriteField(fields[i], fields[i+1])
+				}
+			default:
+				return "", fmt.Errorf("body_FormData: unsupported fields of type %T", fields)
 			}
 
 			_ = writer.Close()
 			g.bodyDataHeader = writer.FormDataContentType()
 
-			return data.String()
+			return data.String(), nil
 		},
 
 		// FakeIt / Product
@@ -477,3 +582,9 @@ func (g *FuncMapGenerator) newFuncMap() *template.FuncMap {
 		"fakeit_SongGenre":  g.localFaker.SongGenre,
 	}
 }
+
+// hashString returns the raw digest of the string with the given hash.
+func hashString(h hash.Hash, s string) string {
+	h.Write([]byte(s))
+	return string(h.Sum(nil))
+}